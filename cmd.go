@@ -2,33 +2,127 @@ package goext
 
 import (
 	"errors"
-	"os/exec"
-	"regexp"
+	"fmt"
+	"strings"
 )
 
-var argumentsRegex = regexp.MustCompile(`[^\s"]+|"((\\"|[^"])*)"`)
+// The error that is returned when command line arguments contain a quote that is not closed.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// An error of a command that exited with an exit code (like *exec.ExitError).
+type cmdExitError interface {
+	error
+	ExitCode() int
+}
 
 type cmdNamespace int
 
 // Contains methods regarding commands.
 var Cmd cmdNamespace = 0
 
-// Splits command line arguments into a slice of strings, respecting quoted substrings.
+// Splits command line arguments into a slice of strings following the POSIX shell rules
+// (single and double quotes, backslash escapes and adjacent quoted parts).
+// Unterminated quotes are closed at the end of the input, use ParseArgs to get an error instead.
 func (cmdNamespace) SplitArgs(arguments ...string) []string {
 	finalArgs := []string{}
 	for _, args := range arguments {
-		finalArgs = append(finalArgs, argumentsRegex.FindAllString(args, -1)...)
+		parsedArgs, _ := splitArgsPosix(args)
+		finalArgs = append(finalArgs, parsedArgs...)
 	}
 	return finalArgs
 }
 
+// Splits command line arguments into a slice of strings following the POSIX shell rules
+// (single and double quotes, backslash escapes and adjacent quoted parts).
+// Returns an error if a quote is not closed.
+func (cmdNamespace) ParseArgs(arguments ...string) ([]string, error) {
+	finalArgs := []string{}
+	for _, args := range arguments {
+		parsedArgs, err := splitArgsPosix(args)
+		if err != nil {
+			return nil, err
+		}
+		finalArgs = append(finalArgs, parsedArgs...)
+	}
+	return finalArgs, nil
+}
+
+// Splits command line arguments into a slice of strings following the Windows rules (like CommandLineToArgvW).
+func (cmdNamespace) SplitArgsWindows(arguments ...string) []string {
+	finalArgs := []string{}
+	for _, args := range arguments {
+		finalArgs = append(finalArgs, splitArgsWindows(args)...)
+	}
+	return finalArgs
+}
+
+// Quotes the argument for a POSIX shell if needed so that SplitArgs returns it unchanged.
+func (cmdNamespace) Quote(argument string) string {
+	if argument != "" && strings.IndexFunc(argument, isUnsafePosixRune) < 0 {
+		return argument
+	}
+	return "'" + strings.ReplaceAll(argument, "'", `'\''`) + "'"
+}
+
+// Joins the arguments into a command line for a POSIX shell, quoting them where needed.
+// The first argument is always quoted if it contains a "=" so the shell does not treat it as a variable assignment.
+func (cmdNamespace) JoinArgs(arguments ...string) string {
+	quotedArgs := make([]string, len(arguments))
+	for index, arg := range arguments {
+		quotedArgs[index] = Cmd.Quote(arg)
+		if index == 0 && strings.Contains(arg, "=") && !strings.HasPrefix(quotedArgs[index], "'") {
+			quotedArgs[index] = "'" + arg + "'"
+		}
+	}
+	return strings.Join(quotedArgs, " ")
+}
+
+// Quotes the argument for Windows (CreateProcess and cmd) if needed so that SplitArgsWindows returns it unchanged.
+// Note that cmd still expands variables like %VAR% inside quotes.
+func (cmdNamespace) QuoteWindows(argument string) string {
+	if argument != "" && !strings.ContainsAny(argument, " \t\n\v\"&|<>^()") {
+		return argument
+	}
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	backslashes := 0
+	for index := 0; index < len(argument); index++ {
+		c := argument[index]
+		switch c {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			// Escape the backslashes and the quote
+			quoted.WriteString(strings.Repeat("\\", backslashes*2+1))
+		default:
+			quoted.WriteString(strings.Repeat("\\", backslashes))
+		}
+		backslashes = 0
+		quoted.WriteByte(c)
+	}
+	// Escape the trailing backslashes so they do not escape the closing quote
+	quoted.WriteString(strings.Repeat("\\", backslashes*2))
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// Joins the arguments into a command line for Windows, quoting them where needed.
+func (cmdNamespace) JoinArgsWindows(arguments ...string) string {
+	quotedArgs := make([]string, len(arguments))
+	for index, arg := range arguments {
+		quotedArgs[index] = Cmd.QuoteWindows(arg)
+	}
+	return strings.Join(quotedArgs, " ")
+}
+
 // Gets the exit code from a command error.
 func (cmdNamespace) ErrorExitCode(err error) int {
 	if err == nil {
 		// No error
 		return 0
 	}
-	var exitErr *exec.ExitError
+	var exitErr cmdExitError
 	if errors.As(err, &exitErr) {
 		// The commands exit code
 		return exitErr.ExitCode()
@@ -36,3 +130,134 @@ func (cmdNamespace) ErrorExitCode(err error) int {
 	// Some other error (e.g. command not found)
 	return -1
 }
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Checks if the rune needs quoting in a POSIX shell.
+func isUnsafePosixRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+}
+
+// Splits the value into words like a POSIX shell does (without expansions).
+// On an unterminated quote, the words so far (including the unterminated one) are returned with an error.
+func splitArgsPosix(value string) ([]string, error) {
+	args := []string{}
+	var word strings.Builder
+	// Words can be empty if they consist of empty quotes only
+	inWord := false
+	for index := 0; index < len(value); index++ {
+		c := value[index]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			inWord = true
+			if index+1 < len(value) {
+				index++
+				// A backslash before a newline continues the line
+				if value[index] != '\n' {
+					word.WriteByte(value[index])
+				}
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(value[index+1:], '\'')
+			if end < 0 {
+				word.WriteString(value[index+1:])
+				return append(args, word.String()), fmt.Errorf("%w: single quote at position %d", ErrUnterminatedQuote, index)
+			}
+			word.WriteString(value[index+1 : index+1+end])
+			index += end + 1
+		case c == '"':
+			inWord = true
+			start := index
+			closed := false
+			for index++; index < len(value); index++ {
+				c = value[index]
+				if c == '"' {
+					closed = true
+					break
+				}
+				// Inside double quotes, the backslash only escapes a few characters
+				if c == '\\' && index+1 < len(value) && strings.IndexByte("$`\"\\\n", value[index+1]) >= 0 {
+					index++
+					if value[index] != '\n' {
+						word.WriteByte(value[index])
+					}
+					continue
+				}
+				word.WriteByte(c)
+			}
+			if !closed {
+				return append(args, word.String()), fmt.Errorf("%w: double quote at position %d", ErrUnterminatedQuote, start)
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// Splits the value into arguments like CommandLineToArgvW does on Windows:
+// 2n backslashes before a quote become n backslashes and the quote toggles quoting,
+// 2n+1 backslashes before a quote become n backslashes and a literal quote,
+// two quotes inside quotes become a literal quote and end the quoting and other backslashes are literal.
+func splitArgsWindows(value string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	inQuotes := false
+	backslashes := 0
+	for index := 0; index < len(value); index++ {
+		c := value[index]
+		switch {
+		case c == '\\':
+			inArg = true
+			backslashes++
+			continue
+		case c == '"':
+			inArg = true
+			arg.WriteString(strings.Repeat("\\", backslashes/2))
+			if backslashes%2 == 1 {
+				arg.WriteByte('"')
+			} else if inQuotes && index+1 < len(value) && value[index+1] == '"' {
+				arg.WriteByte('"')
+				inQuotes = false
+				index++
+			} else {
+				inQuotes = !inQuotes
+			}
+			backslashes = 0
+			continue
+		}
+		arg.WriteString(strings.Repeat("\\", backslashes))
+		backslashes = 0
+		if (c == ' ' || c == '\t') && !inQuotes {
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+			continue
+		}
+		inArg = true
+		arg.WriteByte(c)
+	}
+	arg.WriteString(strings.Repeat("\\", backslashes))
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
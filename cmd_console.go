@@ -0,0 +1,83 @@
+package goext
+
+import (
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	consoleColorReset = "\x1b[0m"
+	consoleColorRed   = "\x1b[31m"
+)
+
+// The colors that are used for the prefixes, similar to docker-compose logs.
+var consolePrefixColors = []string{"\x1b[36m", "\x1b[33m", "\x1b[32m", "\x1b[35m", "\x1b[34m", "\x1b[96m", "\x1b[93m", "\x1b[92m", "\x1b[95m", "\x1b[94m"}
+
+// Checks if the console output needs to be formatted line by line.
+func (r *CmdRunner) formatsConsoleOutput() bool {
+	return r.ConsolePrefix != "" || r.ConsoleTimestampFormat != "" || r.ConsoleColors
+}
+
+// Creates a writer that writes formatted lines to the given console file.
+func (r *CmdRunner) newConsoleWriter(console *os.File, isStderr bool) *lineWriter {
+	return r.newConsoleLineWriter(console, isStderr, r.ConsoleColors && consoleSupportsColors(console))
+}
+
+// Creates a writer that writes formatted console lines to the given writer (e.g. to buffer them for the console).
+func (r *CmdRunner) newConsoleLineWriter(writer io.Writer, isStderr bool, useColors bool) *lineWriter {
+	return newLineWriter(func(line string) {
+		io.WriteString(writer, r.formatConsoleLine(line, isStderr, useColors))
+	}, 0)
+}
+
+// Formats a line with the configured prefix, timestamp and colors.
+func (r *CmdRunner) formatConsoleLine(line string, isStderr bool, useColors bool) string {
+	var sb strings.Builder
+	if r.ConsolePrefix != "" || r.ConsoleTimestampFormat != "" {
+		if useColors {
+			sb.WriteString(consolePrefixColor(r.ConsolePrefix))
+		}
+		if r.ConsoleTimestampFormat != "" {
+			sb.WriteString(time.Now().Format(r.ConsoleTimestampFormat))
+			sb.WriteString(" ")
+		}
+		if r.ConsolePrefix != "" {
+			sb.WriteString(r.ConsolePrefix)
+			sb.WriteString(" ")
+		}
+		if useColors {
+			sb.WriteString(consoleColorReset)
+		}
+	}
+	if useColors && isStderr {
+		sb.WriteString(consoleColorRed)
+		sb.WriteString(line)
+		sb.WriteString(consoleColorReset)
+	} else {
+		sb.WriteString(line)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Gets a color for the prefix that stays the same for the same prefix.
+func consolePrefixColor(prefix string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(prefix))
+	return consolePrefixColors[hash.Sum32()%uint32(len(consolePrefixColors))]
+}
+
+// Checks if colors should be used: the output must be a terminal and NO_COLOR must not be set.
+func consoleSupportsColors(console *os.File) bool {
+	if value, ok := os.LookupEnv("NO_COLOR"); ok && value != "" {
+		return false
+	}
+	info, err := console.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
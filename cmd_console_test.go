@@ -0,0 +1,41 @@
+package goext
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestCmdRunnerFormatConsoleLine(t *testing.T) {
+	runner := NewCmdRunner().WithConsolePrefix("build |")
+	if line := runner.formatConsoleLine("hello", false, false); line != "build | hello\n" {
+		t.Errorf("Expected line to be %q but got %q", "build | hello\n", line)
+	}
+
+	runner = runner.WithConsoleTimestamp("15:04:05")
+	if line := runner.formatConsoleLine("hello", false, false); !regexp.MustCompile(`^\d\d:\d\d:\d\d build \| hello\n$`).MatchString(line) {
+		t.Errorf("Expected line with timestamp and prefix but got %q", line)
+	}
+
+	runner = NewCmdRunner().WithConsolePrefix("build |").WithConsoleColors()
+	expected := consolePrefixColor("build |") + "build | " + consoleColorReset + consoleColorRed + "failed" + consoleColorReset + "\n"
+	if line := runner.formatConsoleLine("failed", true, true); line != expected {
+		t.Errorf("Expected line to be %q but got %q", expected, line)
+	}
+}
+
+func TestConsoleSupportsColors(t *testing.T) {
+	file, err := os.CreateTemp("", "console")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if consoleSupportsColors(file) {
+		t.Errorf("Expected no colors for a file")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if consoleSupportsColors(os.Stdout) {
+		t.Errorf("Expected no colors if NO_COLOR is set")
+	}
+}
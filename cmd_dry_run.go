@@ -0,0 +1,79 @@
+package goext
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Enables the dry-run mode for all CmdRunners.
+var cmdGlobalDryRun atomic.Bool
+
+// Enables or disables the dry-run mode for all CmdRunners.
+// In dry-run mode, commands are not run but printed and a successful result is returned.
+func (cmdNamespace) SetDryRun(dryRun bool) {
+	cmdGlobalDryRun.Store(dryRun)
+}
+
+// Checks if the dry-run mode is enabled for all CmdRunners.
+func (cmdNamespace) IsDryRun() bool {
+	return cmdGlobalDryRun.Load()
+}
+
+// Checks if the runner runs in dry-run mode, either by itself or globally.
+func (r *CmdRunner) isDryRun() bool {
+	return r.DryRun || cmdGlobalDryRun.Load()
+}
+
+// Writes the fully resolved command instead of running it and captures the fake output.
+func (r *CmdRunner) writeDryRun(spec *CmdSpec, buffers cmdOutputBuffers) error {
+	writer := r.DryRunWriter
+	if writer == nil {
+		writer = os.Stdout
+	}
+	if _, err := fmt.Fprintf(writer, "[dry-run] %s\n", maskSecrets(r.describeCmd(spec), r.secretValues(spec.Env))); err != nil {
+		return err
+	}
+	for _, buffer := range []*outputBuffer{buffers.stdout, buffers.combined} {
+		if buffer != nil {
+			buffer.WriteString(r.DryRunStdout)
+		}
+	}
+	return nil
+}
+
+// Describes the command as a shell-like line with the working directory and the environment changes.
+func (r *CmdRunner) describeCmd(spec *CmdSpec) string {
+	var parts []string
+	if spec.WorkingDirectory != "" {
+		parts = append(parts, "cd "+formatCommandLine([]string{spec.WorkingDirectory}), "&&")
+	}
+	if r.CleanEnv {
+		parts = append(parts, "env", "-i")
+		// Show the values of the inherited variables as they are passed explicitly
+		for _, key := range r.InheritEnvKeys {
+			if value, ok := os.LookupEnv(key); ok {
+				parts = append(parts, key+"="+formatCommandLine([]string{value}))
+			}
+		}
+	} else if len(r.RemovedEnvKeys) > 0 {
+		parts = append(parts, "env")
+		for _, key := range r.RemovedEnvKeys {
+			parts = append(parts, "-u", formatCommandLine([]string{key}))
+		}
+	}
+	for key, value := range MapSortedByKey(r.AdditionalEnv) {
+		parts = append(parts, key+"="+formatCommandLine([]string{value}))
+	}
+	// Show the resulting PATH if it was changed
+	if len(r.PathPrepend) > 0 || len(r.PathAppend) > 0 {
+		for _, entry := range spec.Env {
+			if key, value := splitEnvEntry(entry); envKeysEqual(key, "PATH") {
+				parts = append(parts, key+"="+formatCommandLine([]string{value}))
+			}
+		}
+	}
+	parts = append(parts, formatCommandLine(append([]string{spec.Executable}, spec.Arguments...)))
+	return strings.Join(parts, " ")
+}
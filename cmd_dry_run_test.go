@@ -0,0 +1,69 @@
+package goext
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCmdRunnerWithDryRun(t *testing.T) {
+	var dryRunOutput strings.Builder
+	runner := NewCmdRunner().
+		WithDryRun().
+		WithDryRunWriter(&dryRunOutput).
+		WithDryRunStdout("v1.2.3").
+		WithWorkingDirectory("my dir").
+		WithEnv("B_VAR", "b").
+		WithEnv("A_VAR", "a value")
+	result, err := runner.RunResult("this-executable-does-not-exist", "tag", "release 1")
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "v1.2.3" || result.Pid != 0 {
+		t.Errorf("Expected a fake success but got exit code %d, stdout %q and pid %d", result.ExitCode, result.Stdout, result.Pid)
+	}
+	expected := Ternary(runtime.GOOS == "windows",
+		`[dry-run] cd "my dir" && A_VAR="a value" B_VAR=b this-executable-does-not-exist tag "release 1"`+"\n",
+		`[dry-run] cd 'my dir' && A_VAR='a value' B_VAR=b this-executable-does-not-exist tag 'release 1'`+"\n")
+	if dryRunOutput.String() != expected {
+		t.Errorf("Expected dry-run output to be %q but got %q", expected, dryRunOutput.String())
+	}
+}
+
+func TestCmdGlobalDryRun(t *testing.T) {
+	Cmd.SetDryRun(true)
+	defer Cmd.SetDryRun(false)
+	var dryRunOutput strings.Builder
+	err := NewCmdRunner().WithDryRunWriter(&dryRunOutput).Run("this-executable-does-not-exist")
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if !Cmd.IsDryRun() || dryRunOutput.String() != "[dry-run] this-executable-does-not-exist\n" {
+		t.Errorf("Expected the command to be printed but got %q", dryRunOutput.String())
+	}
+}
+
+func TestCmdRunnerWithDryRunEnv(t *testing.T) {
+	t.Setenv("GOEXT_TEST_INHERITED", "inherited")
+	var dryRunOutput strings.Builder
+	runner := NewCmdRunner().WithDryRun().WithDryRunWriter(&dryRunOutput)
+	runner.WithInheritEnv("GOEXT_TEST_INHERITED").WithEnv("A_VAR", "a").Run("tool")
+	runner.WithoutEnv("GOFLAGS").Run("tool")
+	expected := "[dry-run] env -i GOEXT_TEST_INHERITED=inherited A_VAR=a tool\n[dry-run] env -u GOFLAGS tool\n"
+	if dryRunOutput.String() != expected {
+		t.Errorf("Expected dry-run output to be %q but got %q", expected, dryRunOutput.String())
+	}
+}
+
+func TestCmdRunnerWithDryRunPath(t *testing.T) {
+	binDir := filepath.Join(string(os.PathSeparator), "bin")
+	t.Setenv("PATH", binDir)
+	var dryRunOutput strings.Builder
+	NewCmdRunner().WithDryRun().WithDryRunWriter(&dryRunOutput).WithPathAppend(binDir).Run("tool")
+	expected := "[dry-run] PATH=" + formatCommandLine([]string{binDir}) + " tool\n"
+	if dryRunOutput.String() != expected {
+		t.Errorf("Expected dry-run output to be %q but got %q", expected, dryRunOutput.String())
+	}
+}
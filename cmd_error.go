@@ -0,0 +1,54 @@
+package goext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The error that is returned when a command run by a CmdRunner fails.
+// It wraps the original error so errors.Is, errors.As and Cmd.ErrorExitCode still work.
+type CmdError struct {
+	// The command line that failed.
+	CommandLine string
+	// The executable that failed.
+	Executable string
+	// The arguments that were passed to the executable.
+	Arguments []string
+	// The directory in which the command was run.
+	WorkingDirectory string
+	// The exit code of the command or -1 if the command did not exit normally.
+	ExitCode int
+	// The last lines the command wrote to stderr.
+	StderrTail []string
+	// The original error.
+	Err error
+}
+
+func newCmdError(result *CmdResult, stderrTail []string, err error) *CmdError {
+	return &CmdError{
+		CommandLine:      result.CommandLine,
+		Executable:       result.Executable,
+		Arguments:        result.Arguments,
+		WorkingDirectory: result.WorkingDirectory,
+		ExitCode:         result.ExitCode,
+		StderrTail:       stderrTail,
+		Err:              err,
+	}
+}
+
+func (e *CmdError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "command `%s` failed with exit code %d in %q: %v", e.CommandLine, e.ExitCode, e.WorkingDirectory, e.Err)
+	if len(e.StderrTail) > 0 {
+		sb.WriteString("\nstderr:")
+		for _, line := range e.StderrTail {
+			sb.WriteString("\n  ")
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+func (e *CmdError) Unwrap() error {
+	return e.Err
+}
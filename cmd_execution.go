@@ -0,0 +1,165 @@
+package goext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A single execution of a command by a CmdRunner with all the resources it needs.
+type cmdExecution struct {
+	runner   *CmdRunner
+	ctx      context.Context
+	spec     *CmdSpec
+	handle   CmdHandle
+	buffers  cmdOutputBuffers
+	result   *CmdResult
+	cleanups []func()
+	// Serializes the writes to the output.
+	outputMutex sync.Mutex
+	// Replaces the configured input of the runner if set (e.g. in pipelines).
+	stdinOverride io.Reader
+	// Replaces the configured stdout writers of the runner if set (e.g. in pipelines).
+	stdoutOverride io.Writer
+	// The command is only printed but not run.
+	dryRun bool
+	// The error of the command that is available to the cleanups.
+	err error
+	// The secrets that are masked in the output and the result.
+	secrets []string
+}
+
+// Prepares the execution of the command without starting it.
+func (r *CmdRunner) newExecution(ctx context.Context, buffers cmdOutputBuffers, executable string, arguments ...string) *cmdExecution {
+	execution := &cmdExecution{runner: r, ctx: ctx, buffers: buffers}
+	// Apply the timeout if needed
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		execution.ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		execution.cleanups = append(execution.cleanups, cancel)
+	}
+	execution.spec = r.newSpec(execution.ctx, executable, arguments...)
+	execution.result = newCmdResult(execution.spec)
+	// Mask the secrets in the command line, the command itself gets the real arguments
+	execution.secrets = r.secretValues(execution.spec.Env)
+	execution.spec.Secrets = execution.secrets
+	if len(execution.secrets) > 0 {
+		execution.result.CommandLine = maskSecrets(execution.result.CommandLine, execution.secrets)
+		execution.result.Arguments = make([]string, len(execution.spec.Arguments))
+		for index, argument := range execution.spec.Arguments {
+			execution.result.Arguments[index] = maskSecrets(argument, execution.secrets)
+		}
+	}
+	for _, buffer := range []*outputBuffer{buffers.stdout, buffers.stderr, buffers.combined} {
+		if buffer != nil {
+			buffer.setLimit(r.OutputLimit, r.OutputLimitMode)
+		}
+	}
+	// Keep the end of stderr for the error
+	if r.ErrorStderrLines > 0 {
+		execution.buffers.stderrTail = newTailWriter(r.ErrorStderrLines, r.stderrTailLineLimit())
+	}
+	return execution
+}
+
+// Prepares the input and output and starts the command.
+func (e *cmdExecution) start() error {
+	if e.runner.isDryRun() {
+		e.dryRun = true
+		e.result.StartTime = time.Now()
+		return e.runner.writeDryRun(e.spec, e.buffers)
+	}
+	if e.stdinOverride != nil {
+		e.spec.Stdin = e.stdinOverride
+	} else {
+		stdin, closeStdin, err := e.runner.prepareStdin()
+		if err != nil {
+			return err
+		}
+		e.cleanups = append(e.cleanups, closeStdin)
+		e.spec.Stdin = stdin
+	}
+
+	logStdout, logStderr, err := e.openLog()
+	if err != nil {
+		return err
+	}
+	stdoutWriter, stderrWriter, cleanup, err := e.runner.prepareWriters(e.buffers, logStdout, logStderr, &e.outputMutex)
+	if err != nil {
+		return err
+	}
+	e.cleanups = append(e.cleanups, cleanup)
+	e.spec.Stdout = e.maskOutput(stdoutWriter)
+	if e.stdoutOverride != nil {
+		e.spec.Stdout = e.stdoutOverride
+	}
+	e.spec.Stderr = e.maskOutput(stderrWriter)
+
+	e.result.StartTime = time.Now()
+	e.handle, err = e.runner.executor().Start(e.spec)
+	if err != nil {
+		return err
+	}
+	e.result.Pid = e.handle.Pid()
+	return nil
+}
+
+// Waits for the command if it was started successfully, releases all resources and builds the result.
+func (e *cmdExecution) finish(startErr error) (*CmdResult, error) {
+	r := e.runner
+	err := startErr
+	if err == nil {
+		if e.dryRun {
+			e.result.ExitCode = 0
+		} else {
+			err = e.handle.Wait()
+		}
+	}
+	e.result.EndTime = time.Now()
+	if e.result.StartTime.IsZero() {
+		e.result.StartTime = e.result.EndTime
+	}
+	e.result.Duration = e.result.EndTime.Sub(e.result.StartTime)
+	// Check the context before the cleanups as they cancel it
+	err = r.contextError(e.ctx, err)
+	if e.handle != nil {
+		e.result.ExitCode = e.handle.ExitCode()
+	}
+	// Ignore the error if the command exited on its own with an allowed exit code
+	var exitErr cmdExitError
+	if errors.As(err, &exitErr) && e.ctx.Err() == nil && slices.Contains(r.AllowedExitCodes, e.result.ExitCode) {
+		err = nil
+	}
+	e.err = err
+	// Release the resources in reverse order (this also flushes the writers and writes the log footer)
+	for index := len(e.cleanups) - 1; index >= 0; index-- {
+		e.cleanups[index]()
+	}
+	e.result.Stdout = r.bufferString(e.buffers.stdout)
+	e.result.Stderr = r.bufferString(e.buffers.stderr)
+	e.result.CombinedOutput = r.bufferString(e.buffers.combined)
+	e.result.OutputTruncated = slices.ContainsFunc([]*outputBuffer{e.buffers.stdout, e.buffers.stderr, e.buffers.combined}, func(buffer *outputBuffer) bool {
+		return buffer != nil && buffer.Truncated()
+	})
+	if err != nil {
+		var stderrTail []string
+		if e.buffers.stderrTail != nil {
+			stderrTail = e.buffers.stderrTail.Lines()
+		}
+		return e.result, newCmdError(e.result, stderrTail, maskError(err, e.secrets))
+	}
+	return e.result, nil
+}
+
+// Masks the secrets in the output written to the writer. The rest of the output is written with the cleanups.
+func (e *cmdExecution) maskOutput(writer io.Writer) io.Writer {
+	if len(e.secrets) == 0 {
+		return writer
+	}
+	maskWriter := newMaskWriter(writer, e.secrets)
+	e.cleanups = append(e.cleanups, maskWriter.Flush)
+	return maskWriter
+}
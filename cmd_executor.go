@@ -0,0 +1,183 @@
+package goext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// The specification of a command that is started by a CmdExecutor.
+type CmdSpec struct {
+	// The context that stops the command when it is done.
+	Context context.Context
+	// The executable to run.
+	Executable string
+	// The arguments that are passed to the executable.
+	Arguments []string
+	// The directory in which the command runs, empty for the current directory.
+	WorkingDirectory string
+	// The full environment of the command, nil inherits the environment of the current process.
+	Env []string
+	// The input of the command, nil for no input.
+	Stdin io.Reader
+	// The writer for stdout of the command.
+	Stdout io.Writer
+	// The writer for stderr of the command.
+	Stderr io.Writer
+	// The grace period between asking the command to terminate and killing it when it is stopped.
+	GracePeriod time.Duration
+	// Stops the whole process group (including child processes) when the command is stopped.
+	KillProcessGroup bool
+	// The secrets of the runner that must not be revealed by the executor (e.g. in recordings).
+	Secrets []string
+}
+
+// Starts the commands of a CmdRunner. It can be replaced to run commands differently or to fake them in tests.
+type CmdExecutor interface {
+	// Starts the command that is described by the spec.
+	Start(spec *CmdSpec) (CmdHandle, error)
+}
+
+// A command that was started by a CmdExecutor.
+type CmdHandle interface {
+	// Gets the process id of the command.
+	Pid() int
+	// Waits for the command to finish and returns an error if it failed.
+	Wait() error
+	// Gets the exit code after the command finished or -1 if it did not exit normally.
+	ExitCode() int
+	// Sends the signal to the command.
+	Signal(signal os.Signal) error
+	// Kills the command (and its process group if configured).
+	Kill() error
+	// Asks the command to terminate and kills it after the grace period.
+	Stop() error
+}
+
+// The executor that runs real processes. It is used by all CmdRunners without an executor.
+var CmdExecExecutor CmdExecutor = execCmdExecutor{}
+
+////////////////////////////////////////////////////////////
+// Exec Executor
+////////////////////////////////////////////////////////////
+
+type execCmdExecutor struct{}
+
+func (execCmdExecutor) Start(spec *CmdSpec) (CmdHandle, error) {
+	ctx := spec.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Search the executable in the PATH of the command if its environment was changed
+	executable := spec.Executable
+	if spec.Env != nil {
+		if path, err := lookPathInEnv(executable, spec.Env, spec.WorkingDirectory); err == nil {
+			executable = path
+		}
+	}
+	cmd := exec.CommandContext(ctx, executable, spec.Arguments...)
+	cmd.Args[0] = spec.Executable
+	cmd.Dir = spec.WorkingDirectory
+	cmd.Env = spec.Env
+	cmd.Stdin = spec.Stdin
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	handle := &execCmdHandle{cmd: cmd, spec: spec}
+	prepareProcessAttributes(cmd, spec.KillProcessGroup)
+	// Stop the command gracefully when the context is done
+	cmd.Cancel = func() error {
+		// Do not wait forever for the output of child processes that outlive the stopped command.
+		// This is only set once the context is done so successful commands still wait for their children.
+		// The field is read by exec after this function returned.
+		if errors.Is(context.Cause(ctx), errCmdKilled) {
+			// A started command was killed on purpose, so there is no grace period
+			cmd.WaitDelay = cmdOutputWaitDelay
+			return handle.Kill()
+		}
+		cmd.WaitDelay = spec.GracePeriod + cmdOutputWaitDelay
+		return handle.Stop()
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+type execCmdHandle struct {
+	cmd  *exec.Cmd
+	spec *CmdSpec
+	// Protects the fields below which are used by Wait and the stop methods concurrently.
+	mutex sync.Mutex
+	// Kills the command after the grace period when it was stopped.
+	killTimer *time.Timer
+	// Wait returned, so the process (and maybe its group id) is gone.
+	waited bool
+}
+
+func (h *execCmdHandle) Pid() int {
+	return h.cmd.Process.Pid
+}
+
+func (h *execCmdHandle) Wait() error {
+	err := h.cmd.Wait()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.waited = true
+	if h.killTimer != nil {
+		h.killTimer.Stop()
+	}
+	return err
+}
+
+func (h *execCmdHandle) ExitCode() int {
+	if h.cmd.ProcessState == nil {
+		return -1
+	}
+	return h.cmd.ProcessState.ExitCode()
+}
+
+func (h *execCmdHandle) Signal(signal os.Signal) error {
+	return h.cmd.Process.Signal(signal)
+}
+
+// Kills the command. With KillProcessGroup, leftover child processes are also killed
+// as long as Wait did not return (e.g. while it waits for their output).
+func (h *execCmdHandle) Kill() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.kill()
+}
+
+func (h *execCmdHandle) Stop() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.waited {
+		return os.ErrProcessDone
+	}
+	if h.spec.GracePeriod <= 0 {
+		return h.kill()
+	}
+	if err := terminateProcess(h.cmd.Process, h.spec.KillProcessGroup); err != nil {
+		// The process cannot be terminated gracefully, so kill it right away
+		return h.kill()
+	}
+	if h.killTimer == nil {
+		h.killTimer = time.AfterFunc(h.spec.GracePeriod, func() {
+			h.Kill()
+		})
+	}
+	return nil
+}
+
+// Kills the command unless it was already waited for, the mutex must be held.
+func (h *execCmdHandle) kill() error {
+	// The process group id might already be reused after the process was reaped
+	if h.waited {
+		return os.ErrProcessDone
+	}
+	return killProcess(h.cmd.Process, h.spec.KillProcessGroup)
+}
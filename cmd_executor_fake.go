@@ -0,0 +1,253 @@
+package goext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// The error that is returned when a FakeCmdExecutor receives a command that was not expected.
+var ErrFakeCmdUnexpected = errors.New("unexpected command")
+
+// The error of a fake command that exited with a non-zero exit code. It works with Cmd.ErrorExitCode.
+type FakeCmdExitError struct {
+	Code int
+}
+
+func (e *FakeCmdExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+func (e *FakeCmdExitError) ExitCode() int {
+	return e.Code
+}
+
+// A command that is expected by a FakeCmdExecutor with its canned output.
+type FakeCmd struct {
+	Executable string
+	Arguments  []string
+	// Matches the command with any arguments.
+	AnyArguments bool
+	Stdout       string
+	Stderr       string
+	ExitCode     int
+	// The error that is returned when the command is started (e.g. to simulate a missing executable).
+	StartErr error
+	// The index of the call that matched this command or -1 if it was not called.
+	callIndex int
+}
+
+// Sets the canned output and exit code of the command.
+func (c *FakeCmd) Return(stdout string, stderr string, exitCode int) *FakeCmd {
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.ExitCode = exitCode
+	return c
+}
+
+// Sets an error that is returned when the command is started.
+func (c *FakeCmd) ReturnStartError(err error) *FakeCmd {
+	c.StartErr = err
+	return c
+}
+
+// Matches the command with any arguments.
+func (c *FakeCmd) WithAnyArguments() *FakeCmd {
+	c.AnyArguments = true
+	return c
+}
+
+func (c *FakeCmd) matches(spec *CmdSpec) bool {
+	return c.callIndex < 0 && c.Executable == spec.Executable && (c.AnyArguments || slices.Equal(c.Arguments, spec.Arguments))
+}
+
+// A command that was received by a FakeCmdExecutor.
+type FakeCmdCall struct {
+	Executable       string
+	Arguments        []string
+	WorkingDirectory string
+	// The full environment of the command, nil if it inherits the environment of the current process.
+	Env []string
+	// The input that was passed to the command, available once the command finished.
+	Stdin string
+}
+
+// Gets the command line of the call.
+func (c FakeCmdCall) CommandLine() string {
+	return formatCommandLine(append([]string{c.Executable}, c.Arguments...))
+}
+
+// An executor that does not run anything but returns canned output for expected commands and records all calls.
+type FakeCmdExecutor struct {
+	// Runs commands that were not expected successfully without output instead of failing them.
+	AllowUnexpected bool
+	mutex           sync.Mutex
+	expectations    []*FakeCmd
+	calls           []FakeCmdCall
+}
+
+// Creates a new FakeCmdExecutor without any expected commands.
+func NewFakeCmdExecutor() *FakeCmdExecutor {
+	return &FakeCmdExecutor{}
+}
+
+// Registers an expected command. Each expected command matches a single call.
+// If the same command is expected multiple times, the calls match the expectations in the order they were registered.
+func (f *FakeCmdExecutor) Expect(executable string, arguments ...string) *FakeCmd {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	expectation := &FakeCmd{Executable: executable, Arguments: arguments, callIndex: -1}
+	f.expectations = append(f.expectations, expectation)
+	return expectation
+}
+
+// Gets all the commands that were received, in order.
+func (f *FakeCmdExecutor) Calls() []FakeCmdCall {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// Gets the command lines of all the commands that were received, in order.
+func (f *FakeCmdExecutor) CommandLines() []string {
+	var commandLines []string
+	for _, call := range f.Calls() {
+		commandLines = append(commandLines, call.CommandLine())
+	}
+	return commandLines
+}
+
+// Checks that all expected commands were called in the order they were registered.
+func (f *FakeCmdExecutor) Verify() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var errs []error
+	lastCallIndex := -1
+	for _, expectation := range f.expectations {
+		commandLine := formatCommandLine(append([]string{expectation.Executable}, expectation.Arguments...))
+		if expectation.callIndex < 0 {
+			errs = append(errs, fmt.Errorf("expected command was not called: %s", commandLine))
+			continue
+		}
+		if expectation.callIndex < lastCallIndex {
+			errs = append(errs, fmt.Errorf("expected command was called out of order: %s", commandLine))
+		}
+		lastCallIndex = expectation.callIndex
+	}
+	return errors.Join(errs...)
+}
+
+func (f *FakeCmdExecutor) Start(spec *CmdSpec) (CmdHandle, error) {
+	if spec.Context != nil && spec.Context.Err() != nil {
+		return nil, spec.Context.Err()
+	}
+	call := FakeCmdCall{
+		Executable:       spec.Executable,
+		Arguments:        slices.Clone(spec.Arguments),
+		WorkingDirectory: spec.WorkingDirectory,
+		Env:              slices.Clone(spec.Env),
+	}
+
+	f.mutex.Lock()
+	f.calls = append(f.calls, call)
+	callIndex := len(f.calls) - 1
+	index := slices.IndexFunc(f.expectations, func(expectation *FakeCmd) bool {
+		return expectation.matches(spec)
+	})
+	var expectation *FakeCmd
+	if index >= 0 {
+		expectation = f.expectations[index]
+		expectation.callIndex = callIndex
+	}
+	pid := len(f.calls)
+	f.mutex.Unlock()
+
+	if expectation == nil && !f.AllowUnexpected {
+		return nil, fmt.Errorf("%w: %s", ErrFakeCmdUnexpected, call.CommandLine())
+	}
+	if expectation != nil && expectation.StartErr != nil {
+		return nil, expectation.StartErr
+	}
+	recordStdin := func(stdin string) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.calls[callIndex].Stdin = stdin
+	}
+	if expectation == nil {
+		return startFakeCmd(spec, pid, "", "", 0, recordStdin), nil
+	}
+	return startFakeCmd(spec, pid, expectation.Stdout, expectation.Stderr, expectation.ExitCode, recordStdin), nil
+}
+
+// Starts a fake command which reads the input and writes the output in the background like a process.
+// Otherwise the writes could block forever, e.g. when they fill a pipe that is only read after the start.
+// The recordStdin function gets the input once it was read and can be nil.
+func startFakeCmd(spec *CmdSpec, pid int, stdout string, stderr string, exitCode int, recordStdin func(stdin string)) *fakeCmdHandle {
+	handle := &fakeCmdHandle{pid: pid, exitCode: exitCode, done: make(chan struct{})}
+	go func() {
+		defer close(handle.done)
+		// Read the input unless it is the console which would block
+		if spec.Stdin != nil && spec.Stdin != os.Stdin {
+			stdin, err := io.ReadAll(spec.Stdin)
+			if err != nil {
+				handle.err = err
+				return
+			}
+			if recordStdin != nil {
+				recordStdin(string(stdin))
+			}
+		}
+		if spec.Stdout != nil {
+			io.WriteString(spec.Stdout, stdout)
+		}
+		if spec.Stderr != nil {
+			io.WriteString(spec.Stderr, stderr)
+		}
+	}()
+	return handle
+}
+
+// A fake command which finishes as soon as its input was read and its canned output was written.
+type fakeCmdHandle struct {
+	pid      int
+	exitCode int
+	// Closed when the input and output are done.
+	done chan struct{}
+	// The error when reading the input failed.
+	err error
+}
+
+func (h *fakeCmdHandle) Pid() int {
+	return h.pid
+}
+
+func (h *fakeCmdHandle) Wait() error {
+	<-h.done
+	if h.err != nil {
+		return h.err
+	}
+	if h.exitCode != 0 {
+		return &FakeCmdExitError{Code: h.exitCode}
+	}
+	return nil
+}
+
+func (h *fakeCmdHandle) ExitCode() int {
+	return h.exitCode
+}
+
+func (h *fakeCmdHandle) Signal(signal os.Signal) error {
+	return nil
+}
+
+func (h *fakeCmdHandle) Kill() error {
+	return nil
+}
+
+func (h *fakeCmdHandle) Stop() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package goext
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFakeCmdExecutor(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("git", "rev-parse", "HEAD").Return("abc123\n", "", 0)
+	executor.Expect("git", "push").Return("", "rejected\n", 1)
+	runner := NewCmdRunner().WithExecutor(executor).WithWorkingDirectory("repo")
+
+	stdout, _, err := runner.RunGetOutput("git", "rev-parse", "HEAD")
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if stdout != "abc123" {
+		t.Errorf("Expected stdout to be %q but got %q", "abc123", stdout)
+	}
+
+	err = runner.Run("git", "push")
+	var cmdErr *CmdError
+	if !errors.As(err, &cmdErr) || Cmd.ErrorExitCode(err) != 1 || !slices.Equal(cmdErr.StderrTail, []string{"rejected"}) {
+		t.Errorf("Expected a command error with exit code 1 and stderr but got %v", err)
+	}
+
+	expected := []string{"git rev-parse HEAD", "git push"}
+	if commandLines := executor.CommandLines(); !slices.Equal(commandLines, expected) {
+		t.Errorf("Expected calls %q but got %q", expected, commandLines)
+	}
+	if workingDirectory := executor.Calls()[0].WorkingDirectory; workingDirectory != "repo" {
+		t.Errorf("Expected working directory to be %q but got %q", "repo", workingDirectory)
+	}
+	if err := executor.Verify(); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+}
+
+func TestFakeCmdExecutorUnexpectedAndVerify(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("first")
+	executor.Expect("second")
+	executor.Expect("third")
+	runner := NewCmdRunner().WithExecutor(executor).WithStdinString("input")
+
+	if err := runner.Run("unknown", "arg"); !errors.Is(err, ErrFakeCmdUnexpected) || Cmd.ErrorExitCode(err) != -1 {
+		t.Errorf("Expected an unexpected command error but got %v", err)
+	}
+	runner.Run("second")
+	runner.Run("first")
+	if calls := executor.Calls(); calls[1].Stdin != "input" {
+		t.Errorf("Expected stdin to be recorded but got %q", calls[1].Stdin)
+	}
+	err := executor.Verify()
+	if err == nil || err.Error() != "expected command was called out of order: second\nexpected command was not called: third" {
+		t.Errorf("Expected a verify error but got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix && !windows
+
+package goext
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Process groups are not supported on this platform.
+func prepareProcessAttributes(cmd *exec.Cmd, killProcessGroup bool) {
+}
+
+// Graceful termination is not supported on this platform, so the process is killed.
+func terminateProcess(process *os.Process, processGroup bool) error {
+	return process.Kill()
+}
+
+// Kills the process.
+func killProcess(process *os.Process, processGroup bool) error {
+	return process.Kill()
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package goext
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in its own process group if the whole group should be stopped.
+func prepareProcessAttributes(cmd *exec.Cmd, killProcessGroup bool) {
+	if killProcessGroup {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Setpgid = true
+	}
+}
+
+// Asks the process (or its process group) to terminate by sending SIGTERM.
+func terminateProcess(process *os.Process, processGroup bool) error {
+	return signalProcess(process, processGroup, syscall.SIGTERM)
+}
+
+// Kills the process (or its process group) by sending SIGKILL.
+func killProcess(process *os.Process, processGroup bool) error {
+	return signalProcess(process, processGroup, syscall.SIGKILL)
+}
+
+func signalProcess(process *os.Process, processGroup bool, signal syscall.Signal) error {
+	if processGroup {
+		// A negative pid sends the signal to every process in the group
+		return syscall.Kill(-process.Pid, signal)
+	}
+	return process.Signal(signal)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package goext
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// Nothing to prepare, the process tree is stopped with taskkill.
+func prepareProcessAttributes(cmd *exec.Cmd, killProcessGroup bool) {
+}
+
+// Asks the process (or its process tree) to terminate with taskkill.
+func terminateProcess(process *os.Process, processGroup bool) error {
+	arguments := []string{"/PID", strconv.Itoa(process.Pid)}
+	if processGroup {
+		arguments = append(arguments, "/T")
+	}
+	return exec.Command("taskkill", arguments...).Run()
+}
+
+// Kills the process (or its process tree).
+func killProcess(process *os.Process, processGroup bool) error {
+	if processGroup {
+		return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(process.Pid)).Run()
+	}
+	return process.Kill()
+}
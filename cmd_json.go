@@ -0,0 +1,119 @@
+package goext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The number of bytes of the output around the error that are added to a CmdDecodeError.
+const cmdDecodeSnippetRadius = 60
+
+// The error that is returned when the output of a command cannot be decoded.
+type CmdDecodeError struct {
+	// The command line that produced the output.
+	CommandLine string
+	// The offset in the output where decoding failed.
+	Offset int64
+	// The part of the output around the offset.
+	Snippet string
+	// The original error.
+	Err error
+}
+
+func (e *CmdDecodeError) Error() string {
+	return fmt.Sprintf("failed to decode the output of `%s` at offset %d: %v, output: %s", e.CommandLine, e.Offset, e.Err, strconv.Quote(e.Snippet))
+}
+
+func (e *CmdDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Runs the command with the runner (nil for the default runner) and decodes stdout as a single JSON value.
+func CmdRunGetJSON[T any](runner *CmdRunner, executable string, arguments ...string) (T, error) {
+	return CmdRunGetJSONContext[T](context.Background(), runner, executable, arguments...)
+}
+
+// Runs the command with the runner (nil for the default runner) and decodes stdout as a single JSON value.
+// The command is killed when the context is done.
+func CmdRunGetJSONContext[T any](ctx context.Context, runner *CmdRunner, executable string, arguments ...string) (T, error) {
+	var value T
+	result, err := runGetStdout(ctx, runner, executable, arguments...)
+	if err != nil {
+		return value, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(result.Stdout))
+	if err := decoder.Decode(&value); err != nil {
+		return value, newCmdDecodeError(result, decoder, err)
+	}
+	// Only whitespace may follow the value (More does not report stray closing brackets)
+	if _, err := decoder.Token(); err != io.EOF {
+		return value, newCmdDecodeError(result, decoder, errors.New("unexpected data after the JSON value"))
+	}
+	return value, nil
+}
+
+// Runs the command with the runner (nil for the default runner) and decodes stdout as a stream of JSON values
+// (concatenated values or JSON Lines).
+func CmdRunGetJSONStream[T any](runner *CmdRunner, executable string, arguments ...string) ([]T, error) {
+	return CmdRunGetJSONStreamContext[T](context.Background(), runner, executable, arguments...)
+}
+
+// Runs the command with the runner (nil for the default runner) and decodes stdout as a stream of JSON values
+// (concatenated values or JSON Lines). The command is killed when the context is done.
+func CmdRunGetJSONStreamContext[T any](ctx context.Context, runner *CmdRunner, executable string, arguments ...string) ([]T, error) {
+	result, err := runGetStdout(ctx, runner, executable, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	values := []T{}
+	decoder := json.NewDecoder(strings.NewReader(result.Stdout))
+	for {
+		var value T
+		if err := decoder.Decode(&value); err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return values, newCmdDecodeError(result, decoder, err)
+		}
+		values = append(values, value)
+	}
+}
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Runs the command and only captures stdout (and stderr for the error).
+func runGetStdout(ctx context.Context, runner *CmdRunner, executable string, arguments ...string) (*CmdResult, error) {
+	if runner == nil {
+		runner = CmdRunners.Default
+	}
+	return runner.run(ctx, cmdOutputBuffers{stdout: &outputBuffer{}, stderr: &outputBuffer{}}, executable, arguments...)
+}
+
+// Creates the error for a failed decoding with a snippet of the output where it failed.
+func newCmdDecodeError(result *CmdResult, decoder *json.Decoder, err error) *CmdDecodeError {
+	offset := decoder.InputOffset()
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		offset = typeErr.Offset
+	}
+	output := result.Stdout
+	offset = min(max(offset, 0), int64(len(output)))
+	start, end := max(0, int(offset)-cmdDecodeSnippetRadius), min(len(output), int(offset)+cmdDecodeSnippetRadius)
+	snippet := output[start:end]
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(output) {
+		snippet += "..."
+	}
+	return &CmdDecodeError{CommandLine: result.CommandLine, Offset: offset, Snippet: snippet, Err: err}
+}
@@ -0,0 +1,106 @@
+package goext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type testJSONItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestCmdRunGetJSON(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("tool", "get").Return(`{"name": "a", "count": 1}`+"\n", "", 0)
+	executor.Expect("tool", "list").Return(`[{"name": "a"}, {"name": "b"}]`, "", 0)
+	runner := NewCmdRunner().WithExecutor(executor)
+
+	item, err := CmdRunGetJSON[testJSONItem](runner, "tool", "get")
+	if err != nil || item != (testJSONItem{Name: "a", Count: 1}) {
+		t.Errorf("Expected the decoded item but got %+v and %v", item, err)
+	}
+	items, err := CmdRunGetJSON[[]testJSONItem](runner, "tool", "list")
+	if err != nil || len(items) != 2 || items[1].Name != "b" {
+		t.Errorf("Expected the decoded items but got %+v and %v", items, err)
+	}
+}
+
+func TestCmdRunGetJSONStream(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("tool", "lines").Return("{\"name\": \"a\"}\n{\"name\": \"b\"}\n", "", 0)
+	executor.Expect("tool", "concatenated").Return("{\n  \"name\": \"a\"\n}\n{\n  \"name\": \"b\"\n}{\"name\": \"c\"}", "", 0)
+	executor.Expect("tool", "empty").Return("", "", 0)
+	runner := NewCmdRunner().WithExecutor(executor)
+
+	tests := []struct {
+		argument string
+		expected []string
+	}{
+		{"lines", []string{"a", "b"}},
+		{"concatenated", []string{"a", "b", "c"}},
+		{"empty", []string{}},
+	}
+	for _, test := range tests {
+		items, err := CmdRunGetJSONStream[testJSONItem](runner, "tool", test.argument)
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		if !slices.Equal(names, test.expected) {
+			t.Errorf("Expected items %q but got %q", test.expected, names)
+		}
+	}
+}
+
+func TestCmdRunGetJSONErrors(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("tool", "invalid").Return(`{"name": "a", "count": x}`, "", 0)
+	executor.Expect("tool", "type").Return(`{"name": "a", "count": "1"}`, "", 0)
+	executor.Expect("tool", "trailing").Return(`{"name": "a"} garbage`, "", 0)
+	executor.Expect("tool", "bracket").Return(`{"name": "a"}]`, "", 0)
+	executor.Expect("tool", "failed").Return("", "broken", 2)
+	runner := NewCmdRunner().WithExecutor(executor)
+
+	tests := []struct {
+		argument string
+		snippet  string
+	}{
+		{"invalid", `{"name": "a", "count": x}`},
+		{"type", `{"name": "a", "count": "1"}`},
+		{"trailing", `{"name": "a"} garbage`},
+		{"bracket", `{"name": "a"}]`},
+	}
+	for _, test := range tests {
+		_, err := CmdRunGetJSON[testJSONItem](runner, "tool", test.argument)
+		var decodeErr *CmdDecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Snippet != test.snippet || decodeErr.CommandLine != "tool "+test.argument {
+			t.Errorf("Expected a decode error with the output but got %v", err)
+		} else if !strings.Contains(err.Error(), "failed to decode the output of `tool "+test.argument+"`") {
+			t.Errorf("Expected a readable error but got %q", err.Error())
+		}
+	}
+	if _, err := CmdRunGetJSON[testJSONItem](runner, "tool", "failed"); Cmd.ErrorExitCode(err) != 2 {
+		t.Errorf("Expected the command error but got %v", err)
+	}
+}
+
+func TestCmdDecodeErrorSnippet(t *testing.T) {
+	output := "[" + strings.Repeat("1,", 50) + "x" + strings.Repeat(",1", 50) + "]"
+	executor := NewFakeCmdExecutor()
+	executor.Expect("tool").Return(output, "", 0)
+	_, err := CmdRunGetJSON[[]int](NewCmdRunner().WithExecutor(executor), "tool")
+	var decodeErr *CmdDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a decode error but got %v", err)
+	}
+	expected := "..." + output[decodeErr.Offset-cmdDecodeSnippetRadius:decodeErr.Offset+cmdDecodeSnippetRadius] + "..."
+	if !strings.Contains(decodeErr.Snippet, "x") || decodeErr.Snippet != expected {
+		t.Errorf("Expected the snippet %q but got %q", expected, decodeErr.Snippet)
+	}
+}
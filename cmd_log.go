@@ -0,0 +1,100 @@
+package goext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// The format of the {timestamp} placeholder in log file path templates.
+	cmdLogFileTimestampFormat = "20060102-150405.000"
+	// The format of the start time in the log header.
+	cmdLogHeaderTimestampFormat = "2006-01-02 15:04:05.000 -0700"
+)
+
+// Checks if the log file path is a template that creates one file per command.
+func (r *CmdRunner) isLogFileTemplate() bool {
+	return strings.Contains(r.LogFilePath, "{name}") || strings.Contains(r.LogFilePath, "{timestamp}")
+}
+
+// Gets the path of the log file for the command by replacing the placeholders of the template.
+func (r *CmdRunner) resolveLogFilePath(executable string, startTime time.Time) string {
+	name := filepath.Base(executable)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.NewReplacer(
+		"{name}", name,
+		"{timestamp}", startTime.Format(cmdLogFileTimestampFormat),
+	).Replace(r.LogFilePath)
+}
+
+// Opens the log file for appending and creates its directory if needed.
+func openLogFile(filePath string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// Opens the log file of the execution, writes the header and prepares the writers for stdout and stderr.
+// The footer is written and the file is closed with the cleanups of the execution.
+func (e *cmdExecution) openLog() (stdoutWriter, stderrWriter io.Writer, err error) {
+	r := e.runner
+	if r.LogFilePath == "" {
+		return nil, nil, nil
+	}
+	startTime := time.Now()
+	e.result.LogFilePath = r.resolveLogFilePath(e.spec.Executable, startTime)
+	logFile, err := openLogFile(e.result.LogFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.cleanups = append(e.cleanups, func() {
+		logFile.Close()
+	})
+	if r.LogHeaders {
+		fmt.Fprintf(logFile, "--- start: %s\n--- command: %s\n--- working directory: %s\n", startTime.Format(cmdLogHeaderTimestampFormat), e.result.CommandLine, e.result.WorkingDirectory)
+		e.cleanups = append(e.cleanups, func() {
+			e.writeLogFooter(logFile)
+		})
+	}
+	if !r.LogStreamTags && r.LogTimestampFormat == "" {
+		return logFile, logFile, nil
+	}
+	// Tag each line and make sure the last line is written before the footer
+	stdoutLineWriter := r.newLogLineWriter(logFile, "stdout")
+	stderrLineWriter := r.newLogLineWriter(logFile, "stderr")
+	e.cleanups = append(e.cleanups, stdoutLineWriter.Flush, stderrLineWriter.Flush)
+	return stdoutLineWriter, stderrLineWriter, nil
+}
+
+// Writes the exit code and the duration of the command to the log file.
+func (e *cmdExecution) writeLogFooter(logFile io.Writer) {
+	// The exit code already tells why the command failed, other errors (like a missing executable) are written
+	var exitErr cmdExitError
+	if e.err != nil && !errors.As(e.err, &exitErr) {
+		fmt.Fprintf(logFile, "--- error: %v\n", maskError(e.err, e.secrets))
+	}
+	fmt.Fprintf(logFile, "--- exit code: %d, duration: %v\n", e.result.ExitCode, e.result.Duration.Round(time.Millisecond))
+}
+
+// Creates a writer that writes the lines to the log file tagged with the stream and/or the timestamp.
+func (r *CmdRunner) newLogLineWriter(logFile io.Writer, stream string) *lineWriter {
+	return newLineWriter(func(line string) {
+		var sb strings.Builder
+		if r.LogTimestampFormat != "" {
+			sb.WriteString(time.Now().Format(r.LogTimestampFormat))
+			sb.WriteString(" ")
+		}
+		if r.LogStreamTags {
+			sb.WriteString("[" + stream + "] ")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		io.WriteString(logFile, sb.String())
+	}, 0)
+}
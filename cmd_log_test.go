@@ -0,0 +1,92 @@
+package goext
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdRunnerWithLogHeaders(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "test_output.log")
+	executable, arguments := testShellCommand("echo out&& echo err>&2&& exit 3")
+	result, err := NewCmdRunner().WithLogFile(logFilePath).WithLogHeaders().WithLogStreamTags().RunResult(executable, arguments...)
+	if Cmd.ErrorExitCode(err) != 3 {
+		t.Errorf("Expected exit code %d but got %v", 3, err)
+	}
+	logContent, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(logContent), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines in the log but got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "--- start: ") || lines[1] != "--- command: "+result.CommandLine || lines[2] != "--- working directory: "+result.WorkingDirectory {
+		t.Errorf("Expected a header but got %q", lines[:3])
+	}
+	// The order of stdout and stderr is not guaranteed as they are written concurrently
+	if outputLines := slices.Sorted(slices.Values(lines[3:5])); !slices.Equal(outputLines, []string{"[stderr] err", "[stdout] out"}) {
+		t.Errorf("Expected tagged output lines but got %q", lines[3:5])
+	}
+	if !strings.HasPrefix(lines[5], "--- exit code: 3, duration: ") {
+		t.Errorf("Expected a footer but got %q", lines[5])
+	}
+	if result.LogFilePath != logFilePath {
+		t.Errorf("Expected log file path %q but got %q", logFilePath, result.LogFilePath)
+	}
+}
+
+func TestCmdRunnerWithLogHeadersStartError(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "test_output.log")
+	NewCmdRunner().WithLogFile(logFilePath).WithLogHeaders().Run("this-executable-does-not-exist")
+	logContent, _ := os.ReadFile(logFilePath)
+	if !strings.Contains(string(logContent), "\n--- error: ") || !strings.HasSuffix(string(logContent), "--- exit code: -1, duration: 0s\n") {
+		t.Errorf("Expected the error in the footer but got %q", string(logContent))
+	}
+}
+
+func TestCmdRunnerWithLogTimestamp(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "test_output.log")
+	executable, arguments := testShellCommand("echo out")
+	if err := NewCmdRunner().WithLogFile(logFilePath).WithLogTimestamp(time.TimeOnly).Run(executable, arguments...); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	logContent, _ := os.ReadFile(logFilePath)
+	if !regexp.MustCompile(`^\d\d:\d\d:\d\d out\n$`).Match(logContent) {
+		t.Errorf("Expected a timestamped line but got %q", string(logContent))
+	}
+}
+
+func TestCmdRunnerWithLogFileTemplate(t *testing.T) {
+	logDir := t.TempDir()
+	runner := NewCmdRunner().WithLogFile(filepath.Join(logDir, "logs", "{name}-{timestamp}.log"))
+	executable, arguments := testShellCommand("echo first")
+	firstResult, err := runner.RunResult(executable, arguments...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	executable, arguments = testShellCommand("echo second")
+	secondResult, err := runner.RunResult(executable, arguments...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(executable), filepath.Ext(executable))
+	pathRegex := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `-\d{8}-\d{6}\.\d{3}\.log$`)
+	for _, result := range []*CmdResult{firstResult, secondResult} {
+		if !pathRegex.MatchString(filepath.Base(result.LogFilePath)) {
+			t.Errorf("Expected the log file name to match %q but got %q", pathRegex, result.LogFilePath)
+		}
+		logContent, _ := os.ReadFile(result.LogFilePath)
+		if strings.TrimSpace(string(logContent)) != result.Stdout {
+			t.Errorf("Expected the log to contain %q but got %q", result.Stdout, string(logContent))
+		}
+	}
+	if firstResult.LogFilePath == secondResult.LogFilePath {
+		t.Errorf("Expected a log file per command but got %q twice", firstResult.LogFilePath)
+	}
+}
@@ -0,0 +1,96 @@
+package goext
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Searches the executable in the PATH of the runner (including its environment changes) like the runner does when it runs the command.
+// Relative paths are resolved against the working directory of the runner. The runner can be nil to use the current environment.
+func (cmdNamespace) LookPath(runner *CmdRunner, executable string) (string, error) {
+	if runner == nil {
+		runner = NewCmdRunner()
+	}
+	path, err := lookPathInEnv(executable, runner.buildEnv(os.Environ()), runner.WorkingDirectory)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Abs(filepath.Join(runner.WorkingDirectory, path))
+	}
+	return path, nil
+}
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Searches the executable in the PATH of the given environment like exec.LookPath does for the current one.
+// Relative paths are resolved against the working directory.
+func lookPathInEnv(executable string, env []string, workingDirectory string) (string, error) {
+	pathValue, pathExt := "", ""
+	for _, entry := range env {
+		key, value := splitEnvEntry(entry)
+		if envKeysEqual(key, "PATH") {
+			pathValue = value
+		} else if envKeysEqual(key, "PATHEXT") {
+			pathExt = value
+		}
+	}
+	// Executables with a path are not searched in the PATH but are kept relative to the working directory
+	if strings.ContainsRune(executable, '/') || (runtime.GOOS == "windows" && strings.ContainsAny(executable, `\:`)) {
+		file := executable
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(workingDirectory, file)
+		}
+		if path, ok := findExecutable(file, pathExt); ok {
+			// Add the extension that might have been found on Windows
+			return executable + strings.TrimPrefix(path, file), nil
+		}
+		return "", &exec.Error{Name: executable, Err: exec.ErrNotFound}
+	}
+	for _, dir := range filepath.SplitList(pathValue) {
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workingDirectory, dir)
+		}
+		if path, ok := findExecutable(filepath.Join(dir, executable), pathExt); ok {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: executable, Err: exec.ErrNotFound}
+}
+
+// Checks if the file is an executable, on Windows the extensions from PATHEXT are tried as well.
+func findExecutable(file string, pathExt string) (string, bool) {
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(file)
+		return file, err == nil && !info.IsDir() && info.Mode()&0111 != 0
+	}
+	if pathExt == "" {
+		pathExt = ".com;.exe;.bat;.cmd"
+	}
+	extensions := strings.Split(strings.ToLower(pathExt), ";")
+	candidates := []string{}
+	// Files that already have an executable extension are used as they are
+	if slices.Contains(extensions, strings.ToLower(filepath.Ext(file))) {
+		candidates = append(candidates, file)
+	}
+	for _, extension := range extensions {
+		if extension != "" {
+			candidates = append(candidates, file+extension)
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,165 @@
+package goext
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Defines which part of the output is kept when the captured output exceeds the limit.
+type CmdOutputLimitMode int
+
+const (
+	// Keeps the first bytes of the output.
+	CMD_OUTPUT_LIMIT_HEAD CmdOutputLimitMode = iota
+	// Keeps the last bytes of the output.
+	CMD_OUTPUT_LIMIT_TAIL
+	// Keeps the first and the last bytes of the output (half of the limit each).
+	CMD_OUTPUT_LIMIT_HEAD_TAIL
+)
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// The max length of a stderr line that is added to the error, the output limit is used if it is smaller.
+const cmdStderrTailLineLimit = 4096
+
+// Gets the max length of the stderr lines that are kept for the error.
+func (r *CmdRunner) stderrTailLineLimit() int {
+	if r.OutputLimit > 0 {
+		return min(r.OutputLimit, cmdStderrTailLineLimit)
+	}
+	return cmdStderrTailLineLimit
+}
+
+// A buffer for captured output that keeps at most the limit of bytes (0 for no limit)
+// and adds a marker where the output was truncated. It can be read while the command writes to it.
+type outputBuffer struct {
+	// Only protects the buffer so it can be read while other writers (e.g. line functions) are called.
+	mutex sync.Mutex
+	limit int
+	mode  CmdOutputLimitMode
+	head  []byte
+	// The end of the output, it can hold up to twice the tail limit before it is compacted.
+	tail  []byte
+	total int64
+}
+
+// Sets the limit and the mode of the buffer.
+func (b *outputBuffer) setLimit(limit int, mode CmdOutputLimitMode) {
+	b.limit = limit
+	b.mode = mode
+}
+
+// Gets the number of bytes that are kept from the start and the end of the output.
+func (b *outputBuffer) limits() (headLimit int, tailLimit int) {
+	switch b.mode {
+	case CMD_OUTPUT_LIMIT_TAIL:
+		return 0, b.limit
+	case CMD_OUTPUT_LIMIT_HEAD_TAIL:
+		return b.limit - b.limit/2, b.limit / 2
+	default:
+		return b.limit, 0
+	}
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	written := len(p)
+	b.total += int64(written)
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return written, nil
+	}
+	headLimit, tailLimit := b.limits()
+	// Fill the head first
+	if count := min(headLimit-len(b.head), len(p)); count > 0 {
+		b.head = append(b.head, p[:count]...)
+		p = p[count:]
+	}
+	if len(p) == 0 || tailLimit == 0 {
+		return written, nil
+	}
+	// Only keep the end of large writes and compact the tail from time to time
+	if len(p) > tailLimit {
+		p = p[len(p)-tailLimit:]
+	}
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > 2*tailLimit {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-tailLimit:]...)
+	}
+	return written, nil
+}
+
+func (b *outputBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// Discards the content of the buffer but keeps the limit.
+func (b *outputBuffer) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.head = b.head[:0]
+	b.tail = b.tail[:0]
+	b.total = 0
+}
+
+// Gets the kept parts of the output, cut at the boundaries of UTF-8 characters if the output was truncated.
+func (b *outputBuffer) parts() (head []byte, tail []byte, truncated bool) {
+	_, tailLimit := b.limits()
+	head, tail = b.head, b.tail[max(0, len(b.tail)-tailLimit):]
+	if int64(len(head)+len(tail)) == b.total {
+		return head, tail, false
+	}
+	// Do not keep parts of cut characters
+	head = trimIncompleteRune(head)
+	for index := 0; index < utf8.UTFMax && len(tail) > 0 && !utf8.RuneStart(tail[0]); index++ {
+		tail = tail[1:]
+	}
+	return head, tail, true
+}
+
+// Checks if parts of the output were dropped.
+func (b *outputBuffer) Truncated() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, _, truncated := b.parts()
+	return truncated
+}
+
+// Gets the kept output with a marker where it was truncated.
+func (b *outputBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	head, tail, truncated := b.parts()
+	if !truncated {
+		return string(head) + string(tail)
+	}
+	marker := truncatedMarker(b.total - int64(len(head)+len(tail)))
+	switch {
+	case len(head) == 0:
+		return marker + "\n" + string(tail)
+	case len(tail) == 0:
+		return string(head) + "\n" + marker
+	default:
+		return string(head) + "\n" + marker + "\n" + string(tail)
+	}
+}
+
+// Gets the marker for the given number of bytes that were dropped from the output.
+func truncatedMarker(count int64) string {
+	return fmt.Sprintf("[... %d bytes truncated ...]", count)
+}
+
+// Removes the bytes of a character at the end of the data that was cut.
+func trimIncompleteRune(data []byte) []byte {
+	for index := 0; index < utf8.UTFMax && len(data) > 0; index++ {
+		if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size != 1 {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
@@ -0,0 +1,89 @@
+package goext
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	tests := []struct {
+		mode     CmdOutputLimitMode
+		limit    int
+		expected string
+	}{
+		{CMD_OUTPUT_LIMIT_HEAD, 0, "0123456789abcdefghij"},
+		{CMD_OUTPUT_LIMIT_HEAD, 20, "0123456789abcdefghij"},
+		{CMD_OUTPUT_LIMIT_HEAD_TAIL, 20, "0123456789abcdefghij"},
+		{CMD_OUTPUT_LIMIT_HEAD, 6, "012345\n[... 14 bytes truncated ...]"},
+		{CMD_OUTPUT_LIMIT_TAIL, 6, "[... 14 bytes truncated ...]\nefghij"},
+		{CMD_OUTPUT_LIMIT_HEAD_TAIL, 7, "0123\n[... 13 bytes truncated ...]\nhij"},
+	}
+	for _, test := range tests {
+		buffer := &outputBuffer{}
+		buffer.setLimit(test.limit, test.mode)
+		for _, part := range []string{"0123", "456789abcdefg", "h", "ij"} {
+			buffer.WriteString(part)
+		}
+		if buffer.String() != test.expected {
+			t.Errorf("Expected output with limit %d and mode %d to be %q but got %q", test.limit, test.mode, test.expected, buffer.String())
+		}
+		if buffer.Truncated() != strings.Contains(test.expected, "truncated") {
+			t.Errorf("Expected truncated to be %t for limit %d and mode %d", !buffer.Truncated(), test.limit, test.mode)
+		}
+	}
+}
+
+func TestOutputBufferCharacters(t *testing.T) {
+	buffer := &outputBuffer{}
+	buffer.setLimit(7, CMD_OUTPUT_LIMIT_HEAD_TAIL)
+	// Each character has 2 bytes so the limits cut them
+	buffer.WriteString("äöüäöüäöü")
+	if expected := "äö\n[... 12 bytes truncated ...]\nü"; buffer.String() != expected {
+		t.Errorf("Expected output to be %q but got %q", expected, buffer.String())
+	}
+	buffer.Reset()
+	buffer.WriteString("ä")
+	if buffer.String() != "ä" || buffer.Truncated() {
+		t.Errorf("Expected output to be %q after reset but got %q", "ä", buffer.String())
+	}
+}
+
+func TestCmdRunnerWithOutputLimit(t *testing.T) {
+	executable, arguments := testShellCommand("echo 0123456789abcdefghij&& echo err>&2")
+	result, err := NewCmdRunner().WithOutputLimit(10, CMD_OUTPUT_LIMIT_TAIL).RunResult(executable, arguments...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if !result.OutputTruncated || !strings.HasPrefix(result.Stdout, "[... ") || !strings.HasSuffix(result.Stdout, "defghij") {
+		t.Errorf("Expected stdout to be truncated but got %q", result.Stdout)
+	}
+	if result.Stderr != "err" {
+		t.Errorf("Expected stderr to be %q but got %q", "err", result.Stderr)
+	}
+
+	stdout, _, err := NewCmdRunner().WithOutputLimit(100, CMD_OUTPUT_LIMIT_HEAD).RunGetOutput(executable, arguments...)
+	if err != nil || stdout != "0123456789abcdefghij" {
+		t.Errorf("Expected the full output but got %q and %v", stdout, err)
+	}
+}
+
+func TestCmdRunnerLongStderrLine(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("noisy").Return("", strings.Repeat("x", 5*1024*1024), 1)
+	err := NewCmdRunner().WithExecutor(executor).Run("noisy")
+	if err == nil || len(err.Error()) > 2*cmdStderrTailLineLimit || !strings.Contains(err.Error(), "bytes truncated ...]") {
+		t.Errorf("Expected an error with a truncated stderr line but got %d bytes", len(err.Error()))
+	}
+
+	// The line functions get lines truncated to the output limit
+	var lines []string
+	executor.Expect("noisy").Return("0123456789abcdefghij\nshort\n", "", 0)
+	err = NewCmdRunner().WithExecutor(executor).WithOutputLimit(10, CMD_OUTPUT_LIMIT_HEAD).WithStdoutLineFunc(func(line string) {
+		lines = append(lines, line)
+	}).Run("noisy")
+	expected := []string{"0123456789 [... 10 bytes truncated ...]", "short"}
+	if err != nil || !slices.Equal(lines, expected) {
+		t.Errorf("Expected lines %q but got %q and %v", expected, lines, err)
+	}
+}
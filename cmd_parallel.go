@@ -0,0 +1,192 @@
+package goext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Defines how the console output of commands that run in parallel is written.
+type CmdParallelOutputMode int
+
+const (
+	// The console output of each command is written at once when the command has finished.
+	CMD_PARALLEL_OUTPUT_BUFFERED CmdParallelOutputMode = iota
+	// The console output is written line by line while the commands run, prefixed with the label of the command.
+	CMD_PARALLEL_OUTPUT_PREFIXED
+)
+
+// A command that is run by a CmdParallelRunner.
+type CmdJob struct {
+	// The label of the command which is used in the output and errors. Defaults to the command line.
+	Label string
+	// The runner that is used to run the command. Defaults to CmdRunners.Default.
+	Runner     *CmdRunner
+	Executable string
+	Arguments  []string
+}
+
+// Runs multiple commands in parallel with a limited concurrency.
+type CmdParallelRunner struct {
+	// The maximum number of commands that run at the same time. Defaults to the number of CPUs.
+	MaxConcurrency int
+	// Stops all running commands and does not start new ones as soon as one command fails.
+	FailFast bool
+	// Defines how the console output of the commands is written.
+	OutputMode CmdParallelOutputMode
+}
+
+// Creates a new CmdParallelRunner which buffers the output and uses as many concurrent commands as there are CPUs.
+func NewCmdParallelRunner() *CmdParallelRunner {
+	return &CmdParallelRunner{}
+}
+
+// Sets the maximum number of commands that run at the same time.
+func (p *CmdParallelRunner) WithMaxConcurrency(maxConcurrency int) *CmdParallelRunner {
+	clone := p.Clone()
+	clone.MaxConcurrency = maxConcurrency
+	return clone
+}
+
+// Enables stopping all commands as soon as one command fails.
+func (p *CmdParallelRunner) WithFailFast() *CmdParallelRunner {
+	clone := p.Clone()
+	clone.FailFast = true
+	return clone
+}
+
+// Sets how the console output of the commands is written.
+func (p *CmdParallelRunner) WithOutputMode(outputMode CmdParallelOutputMode) *CmdParallelRunner {
+	clone := p.Clone()
+	clone.OutputMode = outputMode
+	return clone
+}
+
+// Creates a copy of the parallel runner.
+func (p *CmdParallelRunner) Clone() *CmdParallelRunner {
+	return &CmdParallelRunner{
+		MaxConcurrency: p.MaxConcurrency,
+		FailFast:       p.FailFast,
+		OutputMode:     p.OutputMode,
+	}
+}
+
+// Runs the jobs in parallel and returns their results in the same order.
+// Jobs that were not started because of FailFast have a nil result.
+// The returned error joins the errors of all failed jobs.
+func (p *CmdParallelRunner) Run(jobs ...CmdJob) ([]*CmdResult, error) {
+	return p.RunContext(context.Background(), jobs...)
+}
+
+// Runs the jobs in parallel and returns their results in the same order. All jobs are killed when the context is done.
+// Jobs that were not started because of FailFast have a nil result.
+// The returned error joins the errors of all failed jobs.
+func (p *CmdParallelRunner) RunContext(ctx context.Context, jobs ...CmdJob) ([]*CmdResult, error) {
+	maxConcurrency := p.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+	jobsCtx, cancelJobs := context.WithCancel(ctx)
+	defer cancelJobs()
+
+	results := make([]*CmdResult, len(jobs))
+	jobErrs := make([]error, len(jobs))
+	// Makes sure the console output of different jobs is not mixed
+	consoleMutex := &sync.Mutex{}
+	semaphore := make(chan struct{}, maxConcurrency)
+	var waitGroup sync.WaitGroup
+	for index, job := range jobs {
+		// Wait for a free slot
+		select {
+		case semaphore <- struct{}{}:
+		case <-jobsCtx.Done():
+		}
+		if jobsCtx.Err() != nil {
+			break
+		}
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+			results[index], jobErrs[index] = p.runJob(jobsCtx, job, consoleMutex)
+			if jobErrs[index] != nil && p.FailFast {
+				cancelJobs()
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	// Collect the errors
+	var errs []error
+	for index, err := range jobErrs {
+		if err == nil {
+			continue
+		}
+		// Skip the jobs which were only canceled because another job failed
+		if p.FailFast && ctx.Err() == nil && errors.Is(err, ErrCmdCanceled) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", jobLabel(jobs[index]), err))
+	}
+	return results, errors.Join(errs...)
+}
+
+// Runs a single job and writes its console output according to the output mode.
+func (p *CmdParallelRunner) runJob(ctx context.Context, job CmdJob, consoleMutex *sync.Mutex) (*CmdResult, error) {
+	runner := jobRunner(job)
+	if !runner.OutputToConsole {
+		return runner.RunResultContext(ctx, job.Executable, job.Arguments...)
+	}
+	switch p.OutputMode {
+	case CMD_PARALLEL_OUTPUT_PREFIXED:
+		// The console output is written line by line, so it is not mixed
+		if runner.ConsolePrefix == "" {
+			runner = runner.WithConsolePrefix("[" + jobLabel(job) + "]")
+		}
+		return runner.RunResultContext(ctx, job.Executable, job.Arguments...)
+	default:
+		// Redirect the console output into buffers, formatted like the console output of the runner
+		var stdoutBuffer, stderrBuffer bytes.Buffer
+		var stdoutWriter, stderrWriter io.Writer = &stdoutBuffer, &stderrBuffer
+		var flushes []func()
+		if runner.formatsConsoleOutput() {
+			stdoutLineWriter := runner.newConsoleLineWriter(&stdoutBuffer, false, runner.ConsoleColors && consoleSupportsColors(os.Stdout))
+			stderrLineWriter := runner.newConsoleLineWriter(&stderrBuffer, true, runner.ConsoleColors && consoleSupportsColors(os.Stderr))
+			stdoutWriter, stderrWriter = stdoutLineWriter, stderrLineWriter
+			flushes = append(flushes, stdoutLineWriter.Flush, stderrLineWriter.Flush)
+		}
+		runner = runner.SetConsoleOutput(false).WithStdoutWriter(stdoutWriter).WithStderrWriter(stderrWriter)
+		result, err := runner.RunResultContext(ctx, job.Executable, job.Arguments...)
+		for _, flush := range flushes {
+			flush()
+		}
+		consoleMutex.Lock()
+		defer consoleMutex.Unlock()
+		io.Copy(os.Stdout, &stdoutBuffer)
+		io.Copy(os.Stderr, &stderrBuffer)
+		return result, err
+	}
+}
+
+// Gets the runner of the job or the default runner if no runner is set.
+func jobRunner(job CmdJob) *CmdRunner {
+	if job.Runner == nil {
+		return CmdRunners.Default
+	}
+	return job.Runner
+}
+
+// Gets the label of the job or its command line (with masked secrets like in the result) if no label is set.
+func jobLabel(job CmdJob) string {
+	if job.Label != "" {
+		return job.Label
+	}
+	runner := jobRunner(job)
+	spec := runner.newSpec(context.Background(), job.Executable, job.Arguments...)
+	return maskSecrets(formatCommandLine(append([]string{spec.Executable}, spec.Arguments...)), runner.secretValues(spec.Env))
+}
@@ -0,0 +1,137 @@
+package goext
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdParallelRunner(t *testing.T) {
+	var jobs []CmdJob
+	for index := range 4 {
+		executable, arguments := testShellCommand(testSleepScript(1) + "&& echo job" + strconv.Itoa(index))
+		jobs = append(jobs, CmdJob{Executable: executable, Arguments: arguments})
+	}
+	start := time.Now()
+	results, err := NewCmdParallelRunner().WithMaxConcurrency(4).Run(jobs...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the jobs to run in parallel but they took %v", elapsed)
+	}
+	for index, result := range results {
+		if expected := "job" + strconv.Itoa(index); result.Stdout != expected {
+			t.Errorf("Expected result %d to be %q but got %q", index, expected, result.Stdout)
+		}
+	}
+}
+
+func TestCmdParallelRunnerFailFast(t *testing.T) {
+	failingExecutable, failingArguments := testShellCommand("exit 5")
+	sleepingExecutable, sleepingArguments := testShellCommand(testSleepScript(10))
+	runner := NewCmdRunner().WithKillProcessGroup()
+	start := time.Now()
+	results, err := NewCmdParallelRunner().WithMaxConcurrency(2).WithFailFast().Run(
+		CmdJob{Label: "sleeping", Runner: runner, Executable: sleepingExecutable, Arguments: sleepingArguments},
+		CmdJob{Label: "failing", Runner: runner, Executable: failingExecutable, Arguments: failingArguments},
+		CmdJob{Label: "skipped", Runner: runner, Executable: sleepingExecutable, Arguments: sleepingArguments},
+	)
+	if Cmd.ErrorExitCode(err) != 5 || !strings.HasPrefix(err.Error(), "failing: ") {
+		t.Errorf("Expected only the failing job error but got %v", err)
+	}
+	if errors.Is(err, ErrCmdCanceled) {
+		t.Errorf("Expected the canceled job to not be reported but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the sleeping job to be canceled but it took %v", elapsed)
+	}
+	if results[0] == nil || results[1] == nil || results[2] != nil {
+		t.Errorf("Expected results for the started jobs only but got %v", results)
+	}
+}
+
+func TestCmdParallelRunnerWith(t *testing.T) {
+	parallelRunner := NewCmdParallelRunner()
+	configured := parallelRunner.WithMaxConcurrency(2).WithFailFast().WithOutputMode(CMD_PARALLEL_OUTPUT_PREFIXED)
+	if configured.MaxConcurrency != 2 || !configured.FailFast || configured.OutputMode != CMD_PARALLEL_OUTPUT_PREFIXED {
+		t.Errorf("Expected the options to be set but got %+v", configured)
+	}
+	if parallelRunner.MaxConcurrency != 0 || parallelRunner.FailFast || parallelRunner.OutputMode != CMD_PARALLEL_OUTPUT_BUFFERED {
+		t.Errorf("Expected the original runner to be unchanged but got %+v", parallelRunner)
+	}
+}
+
+func TestCmdParallelRunnerMasksSecretsInLabels(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("tool", "--token", "hunter2").Return("hi\n", "", 3)
+	runner := NewCmdRunner().WithExecutor(executor).WithConsoleOutput().WithSecrets("hunter2")
+	var err error
+	stdout, _ := captureConsole(t, func() {
+		_, err = NewCmdParallelRunner().WithOutputMode(CMD_PARALLEL_OUTPUT_PREFIXED).Run(CmdJob{Runner: runner, Executable: "tool", Arguments: []string{"--token", "hunter2"}})
+	})
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.HasPrefix(err.Error(), "tool --token ***: ") {
+		t.Errorf("Expected an error with a masked label but got %v", err)
+	}
+	if expected := "[tool --token ***] hi\n"; stdout != expected {
+		t.Errorf("Expected console output %q but got %q", expected, stdout)
+	}
+}
+
+// Runs the function and returns what it wrote to the console.
+func captureConsole(t *testing.T, function func()) (string, string) {
+	t.Helper()
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	originalStdout, originalStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+	stdoutChannel, stderrChannel := make(chan string), make(chan string)
+	go func() {
+		content, _ := io.ReadAll(stdoutReader)
+		stdoutChannel <- string(content)
+	}()
+	go func() {
+		content, _ := io.ReadAll(stderrReader)
+		stderrChannel <- string(content)
+	}()
+	defer func() {
+		os.Stdout, os.Stderr = originalStdout, originalStderr
+	}()
+	function()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	return <-stdoutChannel, <-stderrChannel
+}
+
+func TestCmdParallelRunnerBufferedOutput(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("plain").Return("out\n", "err\n", 0)
+	executor.Expect("formatted").Return("out\n", "err\n", 0)
+	runner := NewCmdRunner().WithExecutor(executor).WithConsoleOutput()
+	var err error
+	stdout, stderr := captureConsole(t, func() {
+		_, err = NewCmdParallelRunner().WithMaxConcurrency(1).Run(
+			CmdJob{Runner: runner, Executable: "plain"},
+			CmdJob{Runner: runner.WithConsolePrefix("build |"), Executable: "formatted"},
+		)
+	})
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if expected := "out\nbuild | out\n"; stdout != expected {
+		t.Errorf("Expected stdout to be %q but got %q", expected, stdout)
+	}
+	if expected := "err\nbuild | err\n"; stderr != expected {
+		t.Errorf("Expected stderr to be %q but got %q", expected, stderr)
+	}
+}
@@ -0,0 +1,176 @@
+package goext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A pipeline of commands where the stdout of each stage is connected to the stdin of the next stage (like `a | b | c`).
+// Each stage is run with the settings of its own CmdRunner. The stdout of all stages except the last one
+// is only passed to the next stage, stderr is handled by the runner of the stage as usual.
+// Retries are not applied to the stages of a pipeline.
+type CmdPipeline struct {
+	// Fails the pipeline if any stage fails (like `set -o pipefail`), otherwise only the last stage counts.
+	PipeFail bool
+	stages   []cmdPipelineStage
+}
+
+type cmdPipelineStage struct {
+	runner     *CmdRunner
+	executable string
+	arguments  []string
+}
+
+// The error that is returned when a pipeline fails.
+type CmdPipelineError struct {
+	// The errors of all stages, nil for the stages that succeeded.
+	StageErrors []error
+	// The index of the stage that failed the pipeline.
+	FailedStage int
+}
+
+func (e *CmdPipelineError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "pipeline stage %d of %d failed: %v", e.FailedStage+1, len(e.StageErrors), e.StageErrors[e.FailedStage])
+	for index, err := range e.StageErrors {
+		if err != nil && index != e.FailedStage {
+			fmt.Fprintf(&sb, "\nstage %d of %d also failed: %v", index+1, len(e.StageErrors), err)
+		}
+	}
+	return sb.String()
+}
+
+// Unwraps the error of the stage that failed the pipeline.
+func (e *CmdPipelineError) Unwrap() error {
+	return e.StageErrors[e.FailedStage]
+}
+
+// Creates a new empty pipeline.
+func NewCmdPipeline() *CmdPipeline {
+	return &CmdPipeline{}
+}
+
+// Returns a copy of the pipeline with an added stage which is run with the given runner.
+func (p *CmdPipeline) Pipe(runner *CmdRunner, executable string, arguments ...string) *CmdPipeline {
+	clone := p.Clone()
+	clone.stages = append(clone.stages, cmdPipelineStage{runner: runner, executable: executable, arguments: slices.Clone(arguments)})
+	return clone
+}
+
+// Enables failing the pipeline if any stage fails.
+func (p *CmdPipeline) WithPipeFail() *CmdPipeline {
+	clone := p.Clone()
+	clone.PipeFail = true
+	return clone
+}
+
+// Creates a copy of the pipeline with the same stages.
+func (p *CmdPipeline) Clone() *CmdPipeline {
+	return &CmdPipeline{
+		PipeFail: p.PipeFail,
+		stages:   slices.Clone(p.stages),
+	}
+}
+
+// Runs the pipeline.
+func (p *CmdPipeline) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// Runs the pipeline. All stages are killed when the context is done.
+func (p *CmdPipeline) RunContext(ctx context.Context) error {
+	_, err := p.RunResultsContext(ctx)
+	return err
+}
+
+// Runs the pipeline and returns the stdout of the last stage.
+func (p *CmdPipeline) RunGetOutput() (string, error) {
+	return p.RunGetOutputContext(context.Background())
+}
+
+// Runs the pipeline and returns the stdout of the last stage. All stages are killed when the context is done.
+func (p *CmdPipeline) RunGetOutputContext(ctx context.Context) (string, error) {
+	results, err := p.RunResultsContext(ctx)
+	if len(results) == 0 {
+		return "", err
+	}
+	return results[len(results)-1].Stdout, err
+}
+
+// Runs the pipeline and returns the results of all stages.
+func (p *CmdPipeline) RunResults() ([]*CmdResult, error) {
+	return p.RunResultsContext(context.Background())
+}
+
+// Runs the pipeline and returns the results of all stages. All stages are killed when the context is done.
+func (p *CmdPipeline) RunResultsContext(ctx context.Context) ([]*CmdResult, error) {
+	if len(p.stages) == 0 {
+		return nil, errors.New("the pipeline has no stages")
+	}
+	// Prepare the executions
+	executions := make([]*cmdExecution, len(p.stages))
+	for index, stage := range p.stages {
+		buffers := cmdOutputBuffers{stderr: &outputBuffer{}}
+		if index == len(p.stages)-1 {
+			buffers.stdout = &outputBuffer{}
+		}
+		executions[index] = stage.runner.newExecution(ctx, buffers, stage.executable, stage.arguments...)
+	}
+	// Connect the stages, each pipe end is closed when its stage finished
+	var pipeFiles []*os.File
+	for index := range len(executions) - 1 {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			for _, pipeFile := range pipeFiles {
+				pipeFile.Close()
+			}
+			return nil, err
+		}
+		pipeFiles = append(pipeFiles, reader, writer)
+		executions[index].stdoutOverride = writer
+		executions[index].cleanups = append(executions[index].cleanups, func() { writer.Close() })
+		executions[index+1].stdinOverride = reader
+		executions[index+1].cleanups = append(executions[index+1].cleanups, func() { reader.Close() })
+	}
+	// Start all stages
+	startErrs := make([]error, len(executions))
+	for index, execution := range executions {
+		startErrs[index] = execution.start()
+	}
+	// Wait for all stages concurrently so the pipe of a stage that finished early is closed right away
+	results := make([]*CmdResult, len(executions))
+	stageErrs := make([]error, len(executions))
+	var waitGroup sync.WaitGroup
+	for index, execution := range executions {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			results[index], stageErrs[index] = execution.finish(startErrs[index])
+		}()
+	}
+	waitGroup.Wait()
+	return results, p.pipelineError(stageErrs)
+}
+
+// Decides if the pipeline failed based on the errors of the stages.
+func (p *CmdPipeline) pipelineError(stageErrs []error) error {
+	lastStage := len(stageErrs) - 1
+	if p.PipeFail {
+		// The last failing stage fails the pipeline
+		for index := lastStage; index >= 0; index-- {
+			if stageErrs[index] != nil {
+				return &CmdPipelineError{StageErrors: stageErrs, FailedStage: index}
+			}
+		}
+		return nil
+	}
+	if stageErrs[lastStage] != nil {
+		return &CmdPipelineError{StageErrors: stageErrs, FailedStage: lastStage}
+	}
+	return nil
+}
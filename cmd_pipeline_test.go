@@ -0,0 +1,91 @@
+package goext
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCmdPipeline(t *testing.T) {
+	firstExecutable, firstArguments := testShellCommand("echo apple&& echo banana&& echo cherry")
+	catExecutable, catArguments := testCatCommand()
+	output, err := NewCmdPipeline().
+		Pipe(CmdRunners.Default, firstExecutable, firstArguments...).
+		Pipe(CmdRunners.Default, catExecutable, catArguments...).
+		Pipe(CmdRunners.Default.WithStdinString("ignored"), "sort", Ternary(runtime.GOOS == "windows", "/R", "-r")).
+		RunGetOutput()
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	expected := "cherry" + testNewline + "banana" + testNewline + "apple"
+	if output != expected {
+		t.Errorf("Expected output to be %q but got %q", expected, output)
+	}
+}
+
+func TestCmdPipelineFail(t *testing.T) {
+	failingExecutable, failingArguments := testShellCommand("echo hello&& exit 3")
+	catExecutable, catArguments := testCatCommand()
+	pipeline := NewCmdPipeline().
+		Pipe(CmdRunners.Default, failingExecutable, failingArguments...).
+		Pipe(CmdRunners.Default, catExecutable, catArguments...)
+
+	results, err := pipeline.RunResults()
+	if err != nil {
+		t.Errorf("Expected no error without pipefail but got %v", err)
+	}
+	if len(results) != 2 || results[0].ExitCode != 3 || results[1].Stdout != "hello" {
+		t.Errorf("Expected the results of both stages but got %v", results)
+	}
+
+	_, err = pipeline.WithPipeFail().RunResults()
+	if pipeline.PipeFail {
+		t.Errorf("Expected the original pipeline to be unchanged")
+	}
+	var pipelineErr *CmdPipelineError
+	if !errors.As(err, &pipelineErr) {
+		t.Fatalf("Expected a pipeline error but got %v", err)
+	}
+	if pipelineErr.FailedStage != 0 || pipelineErr.StageErrors[1] != nil {
+		t.Errorf("Expected only the first stage to fail but got %v", err)
+	}
+	if Cmd.ErrorExitCode(err) != 3 {
+		t.Errorf("Expected exit code %d but got %d", 3, Cmd.ErrorExitCode(err))
+	}
+}
+
+func TestCmdPipelineWithFakeExecutor(t *testing.T) {
+	// More output than fits into the buffer of a pipe
+	largeOutput := strings.Repeat("line\n", 100000)
+	executor := NewFakeCmdExecutor()
+	executor.Expect("generate").Return(largeOutput, "", 0)
+	executor.Expect("filter").Return("filtered\n", "", 0)
+	runner := NewCmdRunner().WithExecutor(executor)
+
+	output, err := NewCmdPipeline().
+		Pipe(runner, "generate").
+		Pipe(runner, "filter").
+		RunGetOutput()
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if output != "filtered" {
+		t.Errorf("Expected output to be %q but got %q", "filtered", output)
+	}
+	if calls := executor.Calls(); len(calls) != 2 || calls[1].Stdin != largeOutput {
+		t.Errorf("Expected the output of the first stage to be the input of the second stage")
+	}
+}
+
+func TestCmdPipelinePipe(t *testing.T) {
+	base := NewCmdPipeline().Pipe(CmdRunners.Default, "first")
+	extended := base.Pipe(CmdRunners.Default, "second")
+	other := base.Pipe(CmdRunners.Default, "other")
+	if len(base.stages) != 1 || len(extended.stages) != 2 || len(other.stages) != 2 {
+		t.Errorf("Expected the original pipeline to be unchanged but got %d, %d and %d stages", len(base.stages), len(extended.stages), len(other.stages))
+	}
+	if extended.stages[1].executable != "second" || other.stages[1].executable != "other" {
+		t.Errorf("Expected the copies to have their own stages but got %q and %q", extended.stages[1].executable, other.stages[1].executable)
+	}
+}
@@ -0,0 +1,198 @@
+package goext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// A command that was started in the background by a CmdRunner.
+type CmdProcess struct {
+	execution *cmdExecution
+	// Cancels the context of the command to stop or kill it.
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	result *CmdResult
+	err    error
+}
+
+// The cause of the canceled context when a started command is killed instead of stopped gracefully.
+var errCmdKilled = errors.New("command was killed")
+
+// Starts the command in the background and returns a handle to it.
+func (r *CmdRunner) Start(executable string, arguments ...string) (*CmdProcess, error) {
+	return r.StartContext(context.Background(), executable, arguments...)
+}
+
+// Starts the command in the background and returns a handle to it. The command is killed when the context is done.
+func (r *CmdRunner) StartContext(ctx context.Context, executable string, arguments ...string) (*CmdProcess, error) {
+	// Stopping and killing cancel the context so the output is not awaited forever (like with a canceled context)
+	ctx, cancel := context.WithCancelCause(ctx)
+	execution := r.newExecution(ctx, cmdOutputBuffers{stdout: &outputBuffer{}, stderr: &outputBuffer{}, combined: &outputBuffer{}}, executable, arguments...)
+	if err := execution.start(); err != nil {
+		_, err = execution.finish(err)
+		cancel(nil)
+		return nil, err
+	}
+	process := &CmdProcess{
+		execution: execution,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		process.result, process.err = execution.finish(nil)
+		cancel(nil)
+		close(process.done)
+	}()
+	return process, nil
+}
+
+// Gets the process id of the command.
+func (p *CmdProcess) Pid() int {
+	return p.execution.result.Pid
+}
+
+// Gets a channel that is closed when the command has finished.
+func (p *CmdProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// Waits for the command to finish and returns its result.
+func (p *CmdProcess) Wait() (*CmdResult, error) {
+	<-p.done
+	return p.result, p.err
+}
+
+// Sends the given signal to the command.
+func (p *CmdProcess) Signal(signal os.Signal) error {
+	if p.execution.dryRun {
+		return nil
+	}
+	return p.execution.handle.Signal(signal)
+}
+
+// Kills the command (and its process group if configured in the runner) like a canceled context but without the grace period.
+func (p *CmdProcess) Kill() error {
+	if p.execution.dryRun {
+		return nil
+	}
+	p.cancel(errCmdKilled)
+	if !errors.Is(context.Cause(p.execution.ctx), errCmdKilled) {
+		// The context was already done before (e.g. the command is being stopped), so kill it right away
+		return p.execution.handle.Kill()
+	}
+	return nil
+}
+
+// Stops the command like a canceled context: asks it to terminate and kills it after the grace period of the runner.
+func (p *CmdProcess) Stop() error {
+	if p.execution.dryRun {
+		return nil
+	}
+	p.cancel(context.Canceled)
+	return nil
+}
+
+// Gets the output on stdout that was written so far.
+func (p *CmdProcess) Stdout() string {
+	return p.execution.runner.bufferString(p.execution.buffers.stdout)
+}
+
+// Gets the output on stderr that was written so far.
+func (p *CmdProcess) Stderr() string {
+	return p.execution.runner.bufferString(p.execution.buffers.stderr)
+}
+
+// Gets the output on stdout and stderr combined that was written so far.
+func (p *CmdProcess) CombinedOutput() string {
+	return p.execution.runner.bufferString(p.execution.buffers.combined)
+}
+
+////////////////////////////////////////////////////////////
+// Readiness
+////////////////////////////////////////////////////////////
+
+// The error that is returned when a started command did not become ready.
+var ErrCmdNotReady = errors.New("command did not become ready")
+
+// The interval in which the readiness of a started command is checked.
+const cmdReadyCheckInterval = 50 * time.Millisecond
+
+// A check that decides if a started command is ready.
+type CmdReadyCheck func(process *CmdProcess) bool
+
+// The command is ready when it wrote a line (to stdout or stderr) that matches the pattern.
+func CmdReadyOnOutput(pattern *regexp.Regexp) CmdReadyCheck {
+	return func(process *CmdProcess) bool {
+		return slices.ContainsFunc(StringSplitByNewLine(process.CombinedOutput()), pattern.MatchString)
+	}
+}
+
+// The command is ready when the TCP port on localhost accepts connections.
+func CmdReadyOnPort(port int) CmdReadyCheck {
+	return func(process *CmdProcess) bool {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), cmdReadyCheckInterval)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+}
+
+// The command is ready when the file exists.
+func CmdReadyOnFile(filePath string) CmdReadyCheck {
+	return func(process *CmdProcess) bool {
+		exists, _ := FileExists(filePath)
+		return exists
+	}
+}
+
+// Starts the command in the background and waits until it is ready.
+// If the command does not become ready, it is killed and an error with its output is returned.
+func (r *CmdRunner) StartReady(check CmdReadyCheck, timeout time.Duration, executable string, arguments ...string) (*CmdProcess, error) {
+	process, err := r.Start(executable, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.WaitReady(check, timeout); err != nil {
+		process.Kill()
+		process.Wait()
+		return nil, err
+	}
+	return process, nil
+}
+
+// Waits until the check reports the command as ready.
+// Fails if the timeout is reached or the command exits before it is ready.
+func (p *CmdProcess) WaitReady(check CmdReadyCheck, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(cmdReadyCheckInterval)
+	defer ticker.Stop()
+	for {
+		if check(p) {
+			return nil
+		}
+		select {
+		case <-p.done:
+			// Check a last time as the output might have been written right before exiting
+			if check(p) {
+				return nil
+			}
+			if p.err == nil {
+				return fmt.Errorf("%w, it exited successfully before\noutput:\n%s", ErrCmdNotReady, p.CombinedOutput())
+			}
+			return fmt.Errorf("%w, it exited before: %w\noutput:\n%s", ErrCmdNotReady, p.err, p.CombinedOutput())
+		case <-timer.C:
+			return fmt.Errorf("%w within %v\noutput:\n%s", ErrCmdNotReady, timeout, p.CombinedOutput())
+		case <-ticker.C:
+		}
+	}
+}
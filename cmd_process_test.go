@@ -0,0 +1,141 @@
+package goext
+
+import (
+	"errors"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdRunnerStart(t *testing.T) {
+	executable, arguments := testShellCommand("echo started&& " + testSleepScript(10))
+	process, err := NewCmdRunner().WithKillProcessGroup().Start(executable, arguments...)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if process.Pid() <= 0 {
+		t.Errorf("Expected a pid but got %d", process.Pid())
+	}
+	// Wait for the output while the command is running
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(process.Stdout(), "started") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if process.Stdout() != "started" {
+		t.Errorf("Expected stdout to be %q but got %q", "started", process.Stdout())
+	}
+	select {
+	case <-process.Done():
+		t.Errorf("Expected the command to still run")
+	default:
+	}
+	if err := process.Kill(); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	result, err := process.Wait()
+	if err == nil {
+		t.Errorf("Expected an error for the killed command but got none")
+	}
+	if result.Stdout != "started" {
+		t.Errorf("Expected result stdout to be %q but got %q", "started", result.Stdout)
+	}
+	if result.Duration > 5*time.Second {
+		t.Errorf("Expected the command to be killed early but it ran for %v", result.Duration)
+	}
+}
+
+func TestCmdRunnerStartNotFound(t *testing.T) {
+	process, err := NewCmdRunner().Start("this-executable-does-not-exist")
+	if err == nil || process != nil {
+		t.Errorf("Expected an error and no process but got %v and %v", err, process)
+	}
+}
+
+func TestCmdRunnerStartReadyOnOutput(t *testing.T) {
+	executable, arguments := testShellCommand("echo starting&& echo listening on 8080&& " + testSleepScript(10))
+	process, err := NewCmdRunner().WithKillProcessGroup().StartReady(CmdReadyOnOutput(regexp.MustCompile(`^listening on \d+$`)), 5*time.Second, executable, arguments...)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	process.Kill()
+	process.Wait()
+}
+
+func TestCmdRunnerStartReadyExitsBefore(t *testing.T) {
+	executable, arguments := testShellCommand("echo some failure&& exit 2")
+	_, err := NewCmdRunner().StartReady(CmdReadyOnOutput(regexp.MustCompile("never")), 5*time.Second, executable, arguments...)
+	if !errors.Is(err, ErrCmdNotReady) || Cmd.ErrorExitCode(err) != 2 {
+		t.Errorf("Expected a not ready error with exit code %d but got %v", 2, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "some failure") {
+		t.Errorf("Expected the error to contain the output but got %q", err.Error())
+	}
+}
+
+func TestCmdRunnerStartReadyTimeout(t *testing.T) {
+	executable, arguments := testShellCommand(testSleepScript(10))
+	start := time.Now()
+	_, err := NewCmdRunner().WithKillProcessGroup().StartReady(CmdReadyOnFile("this-file-does-not-exist"), 200*time.Millisecond, executable, arguments...)
+	if !errors.Is(err, ErrCmdNotReady) {
+		t.Errorf("Expected a not ready error but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be killed early but it ran for %v", elapsed)
+	}
+}
+
+func TestCmdProcessWaitReadyOnPortAndFile(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer listener.Close()
+	readyFilePath := "test_ready.txt"
+	defer os.Remove(readyFilePath)
+
+	executable, arguments := testShellCommand("echo ready>" + readyFilePath + "&& " + testSleepScript(10))
+	process, err := NewCmdRunner().WithKillProcessGroup().Start(executable, arguments...)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer process.Wait()
+	defer process.Kill()
+	if err := process.WaitReady(CmdReadyOnFile(readyFilePath), 5*time.Second); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if err := process.WaitReady(CmdReadyOnPort(listener.Addr().(*net.TCPAddr).Port), 5*time.Second); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+}
+
+func TestCmdProcessOutputFromLineFunc(t *testing.T) {
+	executor := NewFakeCmdExecutor()
+	executor.Expect("app").Return("first\nsecond\n", "", 0)
+	var process *CmdProcess
+	started := make(chan struct{})
+	var outputs []string
+	runner := NewCmdRunner().WithExecutor(executor).WithStdoutLineFunc(func(line string) {
+		<-started
+		// Reading the output from a line function must not deadlock
+		outputs = append(outputs, process.Stdout())
+	})
+	process, err := runner.Start("app")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	close(started)
+	select {
+	case <-process.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the command to finish but it is blocked")
+	}
+	if _, err := process.Wait(); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if len(outputs) != 2 || process.Stdout() != "first\nsecond" {
+		t.Errorf("Expected the output to be read in the line function but got %q", outputs)
+	}
+}
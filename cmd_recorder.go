@@ -0,0 +1,227 @@
+package goext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// The error that is returned when a CmdReplayer has no recording for a command.
+var ErrCmdNotRecorded = errors.New("command was not recorded")
+
+// A recorded execution of a command.
+type CmdRecording struct {
+	Executable       string   `json:"executable"`
+	Arguments        []string `json:"arguments"`
+	WorkingDirectory string   `json:"workingDirectory,omitempty"`
+	// The environment variables that were added or changed compared to the environment of the current process.
+	EnvChanged map[string]string `json:"envChanged,omitempty"`
+	// The environment variables of the current process that were removed.
+	EnvRemoved []string `json:"envRemoved,omitempty"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr"`
+	ExitCode   int      `json:"exitCode"`
+	// The error message if the command could not be started.
+	StartError string `json:"startError,omitempty"`
+}
+
+// Checks if the recording is of the command, the secrets of the command are masked in the recording.
+func (r *CmdRecording) matches(spec *CmdSpec) bool {
+	return r.Executable == maskSecrets(spec.Executable, spec.Secrets) && slices.EqualFunc(r.Arguments, spec.Arguments, func(recordedArgument string, argument string) bool {
+		return recordedArgument == maskSecrets(argument, spec.Secrets)
+	})
+}
+
+// Replaces the secrets in the recording so they are not written to the file.
+func (r *CmdRecording) maskSecrets(secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+	r.Executable = maskSecrets(r.Executable, secrets)
+	for index, argument := range r.Arguments {
+		r.Arguments[index] = maskSecrets(argument, secrets)
+	}
+	for key, value := range r.EnvChanged {
+		r.EnvChanged[key] = maskSecrets(value, secrets)
+	}
+	r.Stdout = maskSecrets(r.Stdout, secrets)
+	r.Stderr = maskSecrets(r.Stderr, secrets)
+	r.StartError = maskSecrets(r.StartError, secrets)
+}
+
+////////////////////////////////////////////////////////////
+// Recorder
+////////////////////////////////////////////////////////////
+
+// An executor that runs the commands with another executor and records them to a JSON file.
+// The file is written after each command.
+type CmdRecorder struct {
+	executor   CmdExecutor
+	filePath   string
+	mutex      sync.Mutex
+	recordings []CmdRecording
+}
+
+// Creates a recorder that runs the commands with the given executor (nil for real processes) and records them to the file.
+func NewCmdRecorder(executor CmdExecutor, filePath string) *CmdRecorder {
+	if executor == nil {
+		executor = CmdExecExecutor
+	}
+	return &CmdRecorder{executor: executor, filePath: filePath}
+}
+
+// Gets all the recordings so far.
+func (r *CmdRecorder) Recordings() []CmdRecording {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.recordings)
+}
+
+func (r *CmdRecorder) Start(spec *CmdSpec) (CmdHandle, error) {
+	recording := CmdRecording{
+		Executable:       spec.Executable,
+		Arguments:        slices.Clone(spec.Arguments),
+		WorkingDirectory: spec.WorkingDirectory,
+		ExitCode:         -1,
+	}
+	recording.EnvChanged, recording.EnvRemoved = envDiff(spec.Env)
+	// Capture the output in addition to the original writers
+	handle := &cmdRecorderHandle{recorder: r, recording: recording, secrets: spec.Secrets}
+	recordedSpec := *spec
+	recordedSpec.Stdout = teeWriter(spec.Stdout, &handle.stdout)
+	recordedSpec.Stderr = teeWriter(spec.Stderr, &handle.stderr)
+	innerHandle, err := r.executor.Start(&recordedSpec)
+	if err != nil {
+		handle.recording.StartError = err.Error()
+		return nil, errors.Join(err, r.add(handle.recording, handle.secrets))
+	}
+	handle.CmdHandle = innerHandle
+	return handle, nil
+}
+
+// Adds the recording with the secrets masked and writes all recordings to the file.
+func (r *CmdRecorder) add(recording CmdRecording, secrets []string) error {
+	recording.maskSecrets(secrets)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.recordings = append(r.recordings, recording)
+	return WriteJsonToFile(r.recordings, r.filePath, true)
+}
+
+type cmdRecorderHandle struct {
+	CmdHandle
+	recorder  *CmdRecorder
+	recording CmdRecording
+	secrets   []string
+	stdout    bytes.Buffer
+	stderr    bytes.Buffer
+}
+
+func (h *cmdRecorderHandle) Wait() error {
+	err := h.CmdHandle.Wait()
+	h.recording.Stdout = h.stdout.String()
+	h.recording.Stderr = h.stderr.String()
+	h.recording.ExitCode = h.CmdHandle.ExitCode()
+	return errors.Join(err, h.recorder.add(h.recording, h.secrets))
+}
+
+////////////////////////////////////////////////////////////
+// Replayer
+////////////////////////////////////////////////////////////
+
+// An executor that replays the commands from a file written by a CmdRecorder without running anything.
+// Each recording is replayed once, in the order they were recorded.
+type CmdReplayer struct {
+	mutex      sync.Mutex
+	recordings []CmdRecording
+	replayed   []bool
+}
+
+// Creates a replayer with the recordings from the given file.
+func NewCmdReplayer(filePath string) (*CmdReplayer, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var recordings []CmdRecording
+	if err := json.Unmarshal(data, &recordings); err != nil {
+		return nil, fmt.Errorf("cannot read recordings from %q: %w", filePath, err)
+	}
+	return &CmdReplayer{recordings: recordings, replayed: make([]bool, len(recordings))}, nil
+}
+
+func (r *CmdReplayer) Start(spec *CmdSpec) (CmdHandle, error) {
+	if spec.Context != nil && spec.Context.Err() != nil {
+		return nil, spec.Context.Err()
+	}
+	r.mutex.Lock()
+	index := -1
+	for recordingIndex := range r.recordings {
+		if !r.replayed[recordingIndex] && r.recordings[recordingIndex].matches(spec) {
+			index = recordingIndex
+			r.replayed[index] = true
+			break
+		}
+	}
+	r.mutex.Unlock()
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCmdNotRecorded, formatCommandLine(append([]string{spec.Executable}, spec.Arguments...)))
+	}
+	recording := r.recordings[index]
+	if recording.StartError != "" {
+		return nil, errors.New(recording.StartError)
+	}
+	return startFakeCmd(spec, index+1, recording.Stdout, recording.Stderr, recording.ExitCode, nil), nil
+}
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Writes to both writers, the first one can be nil.
+func teeWriter(writer io.Writer, buffer *bytes.Buffer) io.Writer {
+	if writer == nil {
+		return buffer
+	}
+	return io.MultiWriter(writer, buffer)
+}
+
+// Gets the differences of the given environment compared to the environment of the current process.
+func envDiff(env []string) (changed map[string]string, removed []string) {
+	if env == nil {
+		return nil, nil
+	}
+	currentEnv := envToMap(os.Environ())
+	newEnv := envToMap(env)
+	for key, value := range newEnv {
+		if currentValue, ok := currentEnv[key]; !ok || currentValue != value {
+			if changed == nil {
+				changed = map[string]string{}
+			}
+			changed[key] = value
+		}
+	}
+	for key := range MapSortedByKey(currentEnv) {
+		if _, ok := newEnv[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	return changed, removed
+}
+
+// Converts a list of "key=value" entries to a map where later entries win.
+func envToMap(env []string) map[string]string {
+	envMap := map[string]string{}
+	for _, entry := range env {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			envMap[key] = value
+		}
+	}
+	return envMap
+}
@@ -0,0 +1,69 @@
+package goext
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCmdRecorderAndReplayer(t *testing.T) {
+	recordingFilePath := "test_recording.json"
+	defer os.Remove(recordingFilePath)
+	executable, arguments := testShellCommand("echo recorded&& echo warning>&2&& exit 3")
+
+	// Record a real execution
+	recorder := NewCmdRecorder(nil, recordingFilePath)
+	runner := NewCmdRunner().WithExecutor(recorder).WithEnv("RECORDED_VAR", "value")
+	recordedResult, _ := runner.RunResult(executable, arguments...)
+	recordings := recorder.Recordings()
+	if len(recordings) != 1 || recordings[0].ExitCode != 3 || recordings[0].EnvChanged["RECORDED_VAR"] != "value" || len(recordings[0].EnvRemoved) != 0 {
+		t.Errorf("Expected one recording with exit code and env changes but got %+v", recordings)
+	}
+
+	// Replay it
+	replayer, err := NewCmdReplayer(recordingFilePath)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	runner = NewCmdRunner().WithExecutor(replayer)
+	replayedResult, err := runner.RunResult(executable, arguments...)
+	if Cmd.ErrorExitCode(err) != 3 {
+		t.Errorf("Expected exit code %d but got %v", 3, err)
+	}
+	if replayedResult.Stdout != recordedResult.Stdout || replayedResult.Stderr != recordedResult.Stderr {
+		t.Errorf("Expected replayed output %q/%q but got %q/%q", recordedResult.Stdout, recordedResult.Stderr, replayedResult.Stdout, replayedResult.Stderr)
+	}
+
+	// Each recording is only replayed once
+	if _, err := runner.RunResult(executable, arguments...); !errors.Is(err, ErrCmdNotRecorded) {
+		t.Errorf("Expected a not recorded error but got %v", err)
+	}
+}
+
+func TestCmdRecorderMasksSecrets(t *testing.T) {
+	recordingFilePath := "test_recording_secrets.json"
+	defer os.Remove(recordingFilePath)
+	executable, arguments := testShellCommand("echo topsecret&& echo topsecret>&2")
+
+	recorder := NewCmdRecorder(nil, recordingFilePath)
+	runner := NewCmdRunner().WithExecutor(recorder).WithEnv("SECRET_TOKEN", "othersecret").WithSecretEnv("SECRET_TOKEN").WithSecrets("topsecret")
+	runner.Run(executable, arguments...)
+	content := readTestFile(t, recordingFilePath)
+	if strings.Contains(content, "topsecret") || strings.Contains(content, "othersecret") {
+		t.Errorf("Expected the secrets to be masked in the recording but got %s", content)
+	}
+	recordings := recorder.Recordings()
+	if len(recordings) != 1 || recordings[0].EnvChanged["SECRET_TOKEN"] != cmdSecretMask || !strings.Contains(recordings[0].Stdout, cmdSecretMask) {
+		t.Errorf("Expected a recording with masked secrets but got %+v", recordings)
+	}
+
+	// The replayer matches the masked arguments with the same secrets
+	replayer, err := NewCmdReplayer(recordingFilePath)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := runner.WithExecutor(replayer).Run(executable, arguments...); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+}
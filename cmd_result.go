@@ -0,0 +1,66 @@
+package goext
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+// The result of a command that was run by a CmdRunner.
+type CmdResult struct {
+	// The command line that was run.
+	CommandLine string
+	// The executable that was run.
+	Executable string
+	// The arguments that were passed to the executable.
+	Arguments []string
+	// The directory in which the command was run.
+	WorkingDirectory string
+	// The exit code of the command or -1 if the command did not exit normally.
+	ExitCode int
+	// The output of the command on stdout.
+	Stdout string
+	// The output of the command on stderr.
+	Stderr string
+	// The output of the command on stdout and stderr combined.
+	CombinedOutput string
+	// True if the captured output exceeded the output limit and was truncated.
+	OutputTruncated bool
+	// The time when the command was started.
+	StartTime time.Time
+	// The time when the command finished.
+	EndTime time.Time
+	// The time the command ran.
+	Duration time.Duration
+	// The process id of the command or 0 if it was not started.
+	Pid int
+	// The number of the attempt that produced this result (starting with 1).
+	Attempt int
+	// The path of the log file the output was written to (the template is resolved) or empty if there is none.
+	LogFilePath string
+	// The results of the previous failed attempts if the command was retried.
+	PreviousAttempts []*CmdResult
+}
+
+func newCmdResult(spec *CmdSpec) *CmdResult {
+	workingDirectory := spec.WorkingDirectory
+	if workingDirectory == "" {
+		workingDirectory, _ = os.Getwd()
+	}
+	return &CmdResult{
+		CommandLine:      formatCommandLine(append([]string{spec.Executable}, spec.Arguments...)),
+		Executable:       spec.Executable,
+		Arguments:        spec.Arguments,
+		WorkingDirectory: workingDirectory,
+		ExitCode:         -1,
+		Attempt:          1,
+	}
+}
+
+// Formats the executable and its arguments as a single line for the current platform, quoting arguments where needed.
+func formatCommandLine(args []string) string {
+	if runtime.GOOS == "windows" {
+		return Cmd.JoinArgsWindows(args...)
+	}
+	return Cmd.JoinArgs(args...)
+}
@@ -0,0 +1,106 @@
+package goext
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// A policy that defines if and how often a failed command is retried.
+type CmdRetryPolicy struct {
+	// The maximum number of attempts, including the first one.
+	MaxAttempts int
+	// The delay before the first retry.
+	Delay time.Duration
+	// The factor by which the delay grows after each retry. Values <= 1 keep the delay fixed.
+	Multiplier float64
+	// The maximum delay between two attempts. Zero means no maximum.
+	MaxDelay time.Duration
+	// The fraction (0 to 1) by which each delay is randomly increased or decreased.
+	Jitter float64
+	// Decides if a failed attempt should be retried. Nil retries all failures.
+	RetryIf func(result *CmdResult, err error) bool
+}
+
+// Retries only if the command failed with one of the given exit codes.
+func CmdRetryOnExitCodes(exitCodes ...int) func(result *CmdResult, err error) bool {
+	return func(result *CmdResult, err error) bool {
+		return slices.Contains(exitCodes, result.ExitCode)
+	}
+}
+
+// Retries only if the stdout or stderr of the failed command matches the given pattern.
+func CmdRetryOnOutputMatch(pattern *regexp.Regexp) func(result *CmdResult, err error) bool {
+	return func(result *CmdResult, err error) bool {
+		return pattern.MatchString(result.Stdout) || pattern.MatchString(result.Stderr)
+	}
+}
+
+// Gets the delay before the given retry (starting with 1).
+func (p *CmdRetryPolicy) delay(retry int) time.Duration {
+	delay := float64(p.Delay)
+	if p.Multiplier > 1 {
+		delay *= math.Pow(p.Multiplier, float64(retry-1))
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	// Apply the maximum after the jitter so it is never exceeded
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	return time.Duration(delay)
+}
+
+// Runs the command until it succeeds, the retry policy stops or the context is done.
+func (r *CmdRunner) runWithRetry(ctx context.Context, buffers cmdOutputBuffers, executable string, arguments ...string) (*CmdResult, error) {
+	policy := r.RetryPolicy
+	// The output is needed to decide if an attempt should be retried
+	if policy.RetryIf != nil {
+		if buffers.stdout == nil {
+			buffers.stdout = &outputBuffer{}
+		}
+		if buffers.stderr == nil {
+			buffers.stderr = &outputBuffer{}
+		}
+	}
+	var previousAttempts []*CmdResult
+	for attempt := 1; ; attempt++ {
+		result, err := r.runOnce(ctx, buffers, executable, arguments...)
+		result.Attempt = attempt
+		result.PreviousAttempts = previousAttempts
+		if err == nil || attempt >= policy.MaxAttempts || (policy.RetryIf != nil && !policy.RetryIf(result, err)) {
+			return result, err
+		}
+		// Do not retry if the context is done, the error already tells that it was canceled
+		if ctx.Err() != nil {
+			return result, err
+		}
+		// Wait before the next attempt
+		delay := policy.delay(attempt)
+		if noteErr := r.writeNote(fmt.Sprintf("--- attempt %d of %d failed with exit code %d, retrying in %v", attempt, policy.MaxAttempts, result.ExitCode, delay.Round(time.Millisecond))); noteErr != nil {
+			return result, noteErr
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, r.contextError(ctx, err)
+		case <-timer.C:
+		}
+		// Only keep the output of the latest attempt in the buffers
+		previousAttempts = append(previousAttempts, result)
+		for _, buffer := range []*outputBuffer{buffers.stdout, buffers.stderr, buffers.combined} {
+			if buffer != nil {
+				buffer.Reset()
+			}
+		}
+		if noteErr := r.writeNote(fmt.Sprintf("--- attempt %d of %d", attempt+1, policy.MaxAttempts)); noteErr != nil {
+			return result, noteErr
+		}
+	}
+}
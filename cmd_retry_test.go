@@ -0,0 +1,95 @@
+package goext
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdRetryPolicyDelay(t *testing.T) {
+	policy := CmdRetryPolicy{Delay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second}
+	for retry, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if delay := policy.delay(retry + 1); delay != expected {
+			t.Errorf("Expected delay of retry %d to be %v but got %v", retry+1, expected, delay)
+		}
+	}
+	policy = CmdRetryPolicy{Delay: time.Second, Jitter: 0.5}
+	for range 10 {
+		if delay := policy.delay(1); delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Errorf("Expected delay to be between %v and %v but got %v", 500*time.Millisecond, 1500*time.Millisecond, delay)
+		}
+	}
+	policy = CmdRetryPolicy{Delay: time.Second, MaxDelay: time.Second, Jitter: 0.5}
+	for range 10 {
+		if delay := policy.delay(1); delay < 500*time.Millisecond || delay > time.Second {
+			t.Errorf("Expected delay to be between %v and %v but got %v", 500*time.Millisecond, time.Second, delay)
+		}
+	}
+}
+
+func TestCmdRunnerWithRetry(t *testing.T) {
+	logFilePath := "test_retry.log"
+	defer os.Remove(logFilePath)
+	runner := NewCmdRunner().WithLogFile(logFilePath).WithRetry(CmdRetryPolicy{MaxAttempts: 3, Delay: 10 * time.Millisecond})
+	executable, arguments := testShellCommand("echo failing&& exit 1")
+	result, err := runner.RunResult(executable, arguments...)
+	if Cmd.ErrorExitCode(err) != 1 {
+		t.Errorf("Expected exit code error %d but got %v", 1, err)
+	}
+	if result.Attempt != 3 || len(result.PreviousAttempts) != 2 {
+		t.Errorf("Expected 3 attempts but got %d with %d previous attempts", result.Attempt, len(result.PreviousAttempts))
+	}
+	if result.Stdout != "failing" {
+		t.Errorf("Expected only the output of the last attempt but got %q", result.Stdout)
+	}
+	logContent, _ := os.ReadFile(logFilePath)
+	for _, marker := range []string{"--- attempt 1 of 3 failed with exit code 1", "--- attempt 2 of 3", "--- attempt 3 of 3"} {
+		if !strings.Contains(string(logContent), marker) {
+			t.Errorf("Expected log to contain %q but got %q", marker, logContent)
+		}
+	}
+}
+
+func TestCmdRunnerWithRetryIf(t *testing.T) {
+	runner := NewCmdRunner().WithRetry(CmdRetryPolicy{
+		MaxAttempts: 3,
+		RetryIf:     CmdRetryOnOutputMatch(regexp.MustCompile("connection reset")),
+	})
+	executable, arguments := testShellCommand("echo other error>&2&& exit 1")
+	result, err := runner.RunResult(executable, arguments...)
+	if err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+	if result.Attempt != 1 {
+		t.Errorf("Expected no retry but got %d attempts", result.Attempt)
+	}
+	executable, arguments = testShellCommand("echo connection reset>&2&& exit 1")
+	result, _ = runner.RunResult(executable, arguments...)
+	if result.Attempt != 3 {
+		t.Errorf("Expected %d attempts but got %d", 3, result.Attempt)
+	}
+}
+
+func TestCmdRunnerWithRetryCanceled(t *testing.T) {
+	logFilePath := "test_retry_canceled.log"
+	defer os.Remove(logFilePath)
+	runner := NewCmdRunner().WithLogFile(logFilePath).WithKillProcessGroup().WithRetry(CmdRetryPolicy{MaxAttempts: 3, Delay: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	executable, arguments := testShellCommand(testSleepScript(10))
+	result, err := runner.RunResultContext(ctx, executable, arguments...)
+	if !errors.Is(err, ErrCmdCanceled) || strings.Count(err.Error(), ErrCmdCanceled.Error()) != 1 {
+		t.Errorf("Expected a single canceled error but got %v", err)
+	}
+	if result.Attempt != 1 {
+		t.Errorf("Expected no retry but got %d attempts", result.Attempt)
+	}
+	logContent, _ := os.ReadFile(logFilePath)
+	if strings.Contains(string(logContent), "retrying") {
+		t.Errorf("Expected no retry note but got %q", logContent)
+	}
+}
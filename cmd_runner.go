@@ -1,13 +1,28 @@
 package goext
 
 import (
-	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The time to wait for the output to be closed after a command was killed.
+const cmdOutputWaitDelay = time.Second
+
+var (
+	// The error that is returned when a command was stopped because its timeout or deadline was reached.
+	ErrCmdTimeout = errors.New("command timed out")
+	// The error that is returned when a command was stopped because its context was canceled.
+	ErrCmdCanceled = errors.New("command was canceled")
 )
 
 type cmdRunners struct {
@@ -25,77 +40,96 @@ var CmdRunners cmdRunners = cmdRunners{
 
 // The CmdRunner struct that holds the configuration for running commands.
 type CmdRunner struct {
-	WorkingDirectory      string
-	OutputToConsole       bool
-	SkipPostProcessOutput bool
-	AdditionalEnv         map[string]string
-	LogFilePath           string
+	WorkingDirectory       string
+	OutputToConsole        bool
+	SkipPostProcessOutput  bool
+	AdditionalEnv          map[string]string
+	CleanEnv               bool
+	InheritEnvKeys         []string
+	RemovedEnvKeys         []string
+	PathPrepend            []string
+	PathAppend             []string
+	Secrets                []string
+	SecretEnvKeys          []string
+	LogFilePath            string
+	LogHeaders             bool
+	LogStreamTags          bool
+	LogTimestampFormat     string
+	Timeout                time.Duration
+	GracePeriod            time.Duration
+	KillProcessGroup       bool
+	Stdin                  io.Reader
+	StdinFilePath          string
+	StdoutWriters          []io.Writer
+	StderrWriters          []io.Writer
+	StdoutLineFuncs        []func(line string)
+	StderrLineFuncs        []func(line string)
+	ErrorStderrLines       int
+	OutputLimit            int
+	OutputLimitMode        CmdOutputLimitMode
+	AllowedExitCodes       []int
+	RetryPolicy            *CmdRetryPolicy
+	ConsolePrefix          string
+	ConsoleTimestampFormat string
+	ConsoleColors          bool
+	DryRun                 bool
+	DryRunWriter           io.Writer
+	DryRunStdout           string
+	Executor               CmdExecutor
 }
 
 // Creates a new CmdRunner with the given options.
 func NewCmdRunner() *CmdRunner {
 	cmdRunner := &CmdRunner{
-		AdditionalEnv: make(map[string]string),
+		AdditionalEnv:    make(map[string]string),
+		ErrorStderrLines: 10,
 	}
 	return cmdRunner
 }
 
 // Runs the command with the given options.
 func (r *CmdRunner) Run(executable string, arguments ...string) error {
-	cmd := r.asCmd(executable, arguments...)
-
-	stdoutWriter, stderrWriter, cleanup, err := r.prepareWriters(nil, nil)
-	if err != nil {
-		return err
-	}
-	defer cleanup()
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = stderrWriter
+	return r.RunContext(context.Background(), executable, arguments...)
+}
 
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-	return cmd.Wait()
+// Runs the command with the given options. The command is killed when the context is done.
+func (r *CmdRunner) RunContext(ctx context.Context, executable string, arguments ...string) error {
+	_, err := r.run(ctx, cmdOutputBuffers{}, executable, arguments...)
+	return err
 }
 
 // Runs the command and returns the separate output from stdout and stderr.
+// With an output limit, truncated output contains a marker (use RunResult to check CmdResult.OutputTruncated instead).
 func (r *CmdRunner) RunGetOutput(executable string, arguments ...string) (string, string, error) {
-	cmd := r.asCmd(executable, arguments...)
-
-	var stdoutBuf, stderrBuf bytes.Buffer
-	stdoutWriter, stderrWriter, cleanup, err := r.prepareWriters(&stdoutBuf, &stderrBuf)
-	if err != nil {
-		return "", "", err
-	}
-	defer cleanup()
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = stderrWriter
+	return r.RunGetOutputContext(context.Background(), executable, arguments...)
+}
 
-	err = cmd.Run()
-	if r.SkipPostProcessOutput {
-		return stdoutBuf.String(), stderrBuf.String(), err
-	}
-	return r.processOutputString(stdoutBuf.String()), r.processOutputString(stderrBuf.String()), err
+// Runs the command and returns the separate output from stdout and stderr. The command is killed when the context is done.
+func (r *CmdRunner) RunGetOutputContext(ctx context.Context, executable string, arguments ...string) (string, string, error) {
+	result, err := r.run(ctx, cmdOutputBuffers{stdout: &outputBuffer{}, stderr: &outputBuffer{}}, executable, arguments...)
+	return result.Stdout, result.Stderr, err
 }
 
 // Runs the command and returns the output from stdout and stderr combined.
+// With an output limit, truncated output contains a marker (use RunResult to check CmdResult.OutputTruncated instead).
 func (r *CmdRunner) RunGetCombinedOutput(executable string, arguments ...string) (string, error) {
-	cmd := r.asCmd(executable, arguments...)
+	return r.RunGetCombinedOutputContext(context.Background(), executable, arguments...)
+}
 
-	var outBuf bytes.Buffer
-	stdoutWriter, stderrWriter, cleanup, err := r.prepareWriters(&outBuf, &outBuf)
-	if err != nil {
-		return "", err
-	}
-	defer cleanup()
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = stderrWriter
+// Runs the command and returns the output from stdout and stderr combined. The command is killed when the context is done.
+func (r *CmdRunner) RunGetCombinedOutputContext(ctx context.Context, executable string, arguments ...string) (string, error) {
+	result, err := r.run(ctx, cmdOutputBuffers{combined: &outputBuffer{}}, executable, arguments...)
+	return result.CombinedOutput, err
+}
 
-	err = cmd.Run()
-	if r.SkipPostProcessOutput {
-		return outBuf.String(), err
-	}
-	return r.processOutputString(outBuf.String()), err
+// Runs the command and returns a result with all the details of the execution.
+func (r *CmdRunner) RunResult(executable string, arguments ...string) (*CmdResult, error) {
+	return r.RunResultContext(context.Background(), executable, arguments...)
+}
+
+// Runs the command and returns a result with all the details of the execution. The command is killed when the context is done.
+func (r *CmdRunner) RunResultContext(ctx context.Context, executable string, arguments ...string) (*CmdResult, error) {
+	return r.run(ctx, cmdOutputBuffers{stdout: &outputBuffer{}, stderr: &outputBuffer{}, combined: &outputBuffer{}}, executable, arguments...)
 }
 
 // Sets the working directory for the command.
@@ -141,13 +175,278 @@ func (r *CmdRunner) WithEnvs(envs map[string]string) *CmdRunner {
 	return clone
 }
 
+// Enables running the command with a clean environment that only contains the added and inherited variables.
+func (r *CmdRunner) WithCleanEnv() *CmdRunner {
+	return r.SetCleanEnv(true)
+}
+
+// Sets running the command with a clean environment that only contains the added and inherited variables.
+func (r *CmdRunner) SetCleanEnv(cleanEnv bool) *CmdRunner {
+	clone := r.Clone()
+	clone.CleanEnv = cleanEnv
+	return clone
+}
+
+// Runs the command with a clean environment that only inherits the given variables (and the added ones).
+func (r *CmdRunner) WithInheritEnv(keys ...string) *CmdRunner {
+	clone := r.Clone()
+	clone.CleanEnv = true
+	clone.InheritEnvKeys = append(clone.InheritEnvKeys, keys...)
+	return clone
+}
+
+// Removes the given variables from the environment of the command (including already added ones).
+func (r *CmdRunner) WithoutEnv(keys ...string) *CmdRunner {
+	clone := r.Clone()
+	for _, key := range keys {
+		maps.DeleteFunc(clone.AdditionalEnv, func(additionalKey string, _ string) bool {
+			return envKeysEqual(additionalKey, key)
+		})
+	}
+	clone.RemovedEnvKeys = append(clone.RemovedEnvKeys, keys...)
+	return clone
+}
+
+// Adds directories to the start of the PATH of the command. Relative directories are resolved against the working directory.
+func (r *CmdRunner) WithPathPrepend(dirs ...string) *CmdRunner {
+	clone := r.Clone()
+	// Directories that are prepended later come first
+	clone.PathPrepend = slices.Concat(dirs, clone.PathPrepend)
+	return clone
+}
+
+// Adds directories to the end of the PATH of the command. Relative directories are resolved against the working directory.
+func (r *CmdRunner) WithPathAppend(dirs ...string) *CmdRunner {
+	clone := r.Clone()
+	clone.PathAppend = append(clone.PathAppend, dirs...)
+	return clone
+}
+
+// Adds secret values that are replaced with *** in the output, the log file, the errors and the command lines.
+func (r *CmdRunner) WithSecrets(secrets ...string) *CmdRunner {
+	clone := r.Clone()
+	clone.Secrets = append(clone.Secrets, secrets...)
+	return clone
+}
+
+// Adds environment variables whose values are secrets that are replaced with *** like the ones from WithSecrets.
+func (r *CmdRunner) WithSecretEnv(keys ...string) *CmdRunner {
+	clone := r.Clone()
+	clone.SecretEnvKeys = append(clone.SecretEnvKeys, keys...)
+	return clone
+}
+
 // Sets a file path to which all command output (stdout + stderr) is written.
+// The path can be a template with the placeholders {name} (the executable) and {timestamp} to create one file per command.
 func (r *CmdRunner) WithLogFile(filePath string) *CmdRunner {
 	clone := r.Clone()
 	clone.LogFilePath = filePath
 	return clone
 }
 
+// Enables writing a header (start time, command line and working directory) and a footer (exit code and duration) to the log file for each command.
+func (r *CmdRunner) WithLogHeaders() *CmdRunner {
+	return r.SetLogHeaders(true)
+}
+
+// Sets writing a header (start time, command line and working directory) and a footer (exit code and duration) to the log file for each command.
+func (r *CmdRunner) SetLogHeaders(logHeaders bool) *CmdRunner {
+	clone := r.Clone()
+	clone.LogHeaders = logHeaders
+	return clone
+}
+
+// Enables tagging each line in the log file with the stream ([stdout] or [stderr]) it was written to.
+func (r *CmdRunner) WithLogStreamTags() *CmdRunner {
+	return r.SetLogStreamTags(true)
+}
+
+// Sets tagging each line in the log file with the stream ([stdout] or [stderr]) it was written to.
+func (r *CmdRunner) SetLogStreamTags(logStreamTags bool) *CmdRunner {
+	clone := r.Clone()
+	clone.LogStreamTags = logStreamTags
+	return clone
+}
+
+// Sets the layout (see time.Format) of a timestamp that is written before each line in the log file.
+func (r *CmdRunner) WithLogTimestamp(layout string) *CmdRunner {
+	clone := r.Clone()
+	clone.LogTimestampFormat = layout
+	return clone
+}
+
+// Sets a timeout after which the command is killed.
+func (r *CmdRunner) WithTimeout(timeout time.Duration) *CmdRunner {
+	clone := r.Clone()
+	clone.Timeout = timeout
+	return clone
+}
+
+// Sets the grace period between asking the command to terminate and killing it when it is stopped.
+func (r *CmdRunner) WithGracePeriod(gracePeriod time.Duration) *CmdRunner {
+	clone := r.Clone()
+	clone.GracePeriod = gracePeriod
+	return clone
+}
+
+// Enables stopping the whole process group (including child processes) when the command is stopped.
+func (r *CmdRunner) WithKillProcessGroup() *CmdRunner {
+	return r.SetKillProcessGroup(true)
+}
+
+// Sets stopping the whole process group (including child processes) when the command is stopped.
+func (r *CmdRunner) SetKillProcessGroup(killProcessGroup bool) *CmdRunner {
+	clone := r.Clone()
+	clone.KillProcessGroup = killProcessGroup
+	return clone
+}
+
+// Sets the reader from which the command reads its input.
+// Readers with a known size (like strings.Reader or bytes.Reader) are read from the start for each run,
+// other readers can only be consumed once.
+func (r *CmdRunner) WithStdin(reader io.Reader) *CmdRunner {
+	clone := r.Clone()
+	clone.Stdin = reader
+	clone.StdinFilePath = ""
+	return clone
+}
+
+// Sets the given string as input for the command.
+func (r *CmdRunner) WithStdinString(value string) *CmdRunner {
+	return r.WithStdin(strings.NewReader(value))
+}
+
+// Sets a file path from which the command reads its input.
+func (r *CmdRunner) WithStdinFile(filePath string) *CmdRunner {
+	clone := r.Clone()
+	clone.Stdin = nil
+	clone.StdinFilePath = filePath
+	return clone
+}
+
+// Passes the input of the current process to the command (e.g. for interactive tools).
+func (r *CmdRunner) WithStdinPassthrough() *CmdRunner {
+	return r.WithStdin(os.Stdin)
+}
+
+// Adds a writer to which the stdout of the command is written.
+func (r *CmdRunner) WithStdoutWriter(writer io.Writer) *CmdRunner {
+	clone := r.Clone()
+	clone.StdoutWriters = append(clone.StdoutWriters, writer)
+	return clone
+}
+
+// Adds a writer to which the stderr of the command is written.
+func (r *CmdRunner) WithStderrWriter(writer io.Writer) *CmdRunner {
+	clone := r.Clone()
+	clone.StderrWriters = append(clone.StderrWriters, writer)
+	return clone
+}
+
+// Adds a function that is called for each line the command writes to stdout.
+func (r *CmdRunner) WithStdoutLineFunc(lineFunc func(line string)) *CmdRunner {
+	clone := r.Clone()
+	clone.StdoutLineFuncs = append(clone.StdoutLineFuncs, lineFunc)
+	return clone
+}
+
+// Adds a function that is called for each line the command writes to stderr.
+func (r *CmdRunner) WithStderrLineFunc(lineFunc func(line string)) *CmdRunner {
+	clone := r.Clone()
+	clone.StderrLineFuncs = append(clone.StderrLineFuncs, lineFunc)
+	return clone
+}
+
+// Sets the number of stderr lines that are added to the error when the command fails.
+func (r *CmdRunner) WithErrorStderrLines(lines int) *CmdRunner {
+	clone := r.Clone()
+	clone.ErrorStderrLines = lines
+	return clone
+}
+
+// Sets the maximum number of bytes that are captured for each output (stdout, stderr and combined) and which part of it is kept.
+// A marker is added where the output is truncated, use RunResult and CmdResult.OutputTruncated to check if that happened.
+// The lines passed to the line functions are truncated to the same limit. Other writers (like the console or the log file) still get the full output.
+func (r *CmdRunner) WithOutputLimit(limit int, mode CmdOutputLimitMode) *CmdRunner {
+	clone := r.Clone()
+	clone.OutputLimit = limit
+	clone.OutputLimitMode = mode
+	return clone
+}
+
+// Adds exit codes that are not treated as failure. The exit code is still available in the CmdResult.
+func (r *CmdRunner) WithAllowedExitCodes(exitCodes ...int) *CmdRunner {
+	clone := r.Clone()
+	clone.AllowedExitCodes = append(clone.AllowedExitCodes, exitCodes...)
+	return clone
+}
+
+// Sets the policy that defines if and how failed commands are retried.
+func (r *CmdRunner) WithRetry(retryPolicy CmdRetryPolicy) *CmdRunner {
+	clone := r.Clone()
+	clone.RetryPolicy = &retryPolicy
+	return clone
+}
+
+// Sets a prefix (e.g. a label) that is written before each line of console output.
+func (r *CmdRunner) WithConsolePrefix(prefix string) *CmdRunner {
+	clone := r.Clone()
+	clone.ConsolePrefix = prefix
+	return clone
+}
+
+// Sets the layout (see time.Format) of a timestamp that is written before each line of console output.
+func (r *CmdRunner) WithConsoleTimestamp(layout string) *CmdRunner {
+	clone := r.Clone()
+	clone.ConsoleTimestampFormat = layout
+	return clone
+}
+
+// Enables colored console output. Colors are only used if the output is a terminal and NO_COLOR is not set.
+func (r *CmdRunner) WithConsoleColors() *CmdRunner {
+	return r.SetConsoleColors(true)
+}
+
+// Sets colored console output. Colors are only used if the output is a terminal and NO_COLOR is not set.
+func (r *CmdRunner) SetConsoleColors(consoleColors bool) *CmdRunner {
+	clone := r.Clone()
+	clone.ConsoleColors = consoleColors
+	return clone
+}
+
+// Enables the dry-run mode: commands are not run but printed and a successful result is returned.
+func (r *CmdRunner) WithDryRun() *CmdRunner {
+	return r.SetDryRun(true)
+}
+
+// Sets the dry-run mode: commands are not run but printed and a successful result is returned.
+func (r *CmdRunner) SetDryRun(dryRun bool) *CmdRunner {
+	clone := r.Clone()
+	clone.DryRun = dryRun
+	return clone
+}
+
+// Sets the writer to which the commands are written in dry-run mode (defaults to stdout).
+func (r *CmdRunner) WithDryRunWriter(writer io.Writer) *CmdRunner {
+	clone := r.Clone()
+	clone.DryRunWriter = writer
+	return clone
+}
+
+// Sets the fake stdout that is returned in dry-run mode.
+func (r *CmdRunner) WithDryRunStdout(stdout string) *CmdRunner {
+	clone := r.Clone()
+	clone.DryRunStdout = stdout
+	return clone
+}
+
+// Sets the executor that starts the commands (e.g. a FakeCmdExecutor in tests).
+func (r *CmdRunner) WithExecutor(executor CmdExecutor) *CmdRunner {
+	clone := r.Clone()
+	clone.Executor = executor
+	return clone
+}
+
 // Clones the CmdRunner with its current configuration.
 func (r *CmdRunner) Clone() *CmdRunner {
 	clone := NewCmdRunner()
@@ -155,8 +454,39 @@ func (r *CmdRunner) Clone() *CmdRunner {
 	clone.OutputToConsole = r.OutputToConsole
 	clone.SkipPostProcessOutput = r.SkipPostProcessOutput
 	clone.LogFilePath = r.LogFilePath
+	clone.LogHeaders = r.LogHeaders
+	clone.LogStreamTags = r.LogStreamTags
+	clone.LogTimestampFormat = r.LogTimestampFormat
+	clone.Timeout = r.Timeout
+	clone.GracePeriod = r.GracePeriod
+	clone.KillProcessGroup = r.KillProcessGroup
+	clone.Stdin = r.Stdin
+	clone.StdinFilePath = r.StdinFilePath
+	clone.StdoutWriters = slices.Clone(r.StdoutWriters)
+	clone.StderrWriters = slices.Clone(r.StderrWriters)
+	clone.StdoutLineFuncs = slices.Clone(r.StdoutLineFuncs)
+	clone.StderrLineFuncs = slices.Clone(r.StderrLineFuncs)
+	clone.ErrorStderrLines = r.ErrorStderrLines
+	clone.OutputLimit = r.OutputLimit
+	clone.OutputLimitMode = r.OutputLimitMode
+	clone.AllowedExitCodes = slices.Clone(r.AllowedExitCodes)
+	clone.RetryPolicy = r.RetryPolicy
+	clone.ConsolePrefix = r.ConsolePrefix
+	clone.ConsoleTimestampFormat = r.ConsoleTimestampFormat
+	clone.ConsoleColors = r.ConsoleColors
+	clone.DryRun = r.DryRun
+	clone.DryRunWriter = r.DryRunWriter
+	clone.DryRunStdout = r.DryRunStdout
+	clone.Executor = r.Executor
 	clone.AdditionalEnv = make(map[string]string)
 	maps.Copy(clone.AdditionalEnv, r.AdditionalEnv)
+	clone.CleanEnv = r.CleanEnv
+	clone.InheritEnvKeys = slices.Clone(r.InheritEnvKeys)
+	clone.RemovedEnvKeys = slices.Clone(r.RemovedEnvKeys)
+	clone.PathPrepend = slices.Clone(r.PathPrepend)
+	clone.PathAppend = slices.Clone(r.PathAppend)
+	clone.Secrets = slices.Clone(r.Secrets)
+	clone.SecretEnvKeys = slices.Clone(r.SecretEnvKeys)
 	return clone
 }
 
@@ -164,62 +494,259 @@ func (r *CmdRunner) Clone() *CmdRunner {
 // Internal
 ////////////////////////////////////////////////////////////
 
-func (r *CmdRunner) asCmd(executable string, arguments ...string) *exec.Cmd {
+// The buffers in which the output of a command is captured, nil buffers are not captured.
+type cmdOutputBuffers struct {
+	stdout     *outputBuffer
+	stderr     *outputBuffer
+	combined   *outputBuffer
+	stderrTail *tailWriter
+}
+
+// Runs the command (with retries if configured) and always returns a result, even if the command could not be started.
+func (r *CmdRunner) run(ctx context.Context, buffers cmdOutputBuffers, executable string, arguments ...string) (*CmdResult, error) {
+	if r.RetryPolicy == nil {
+		return r.runOnce(ctx, buffers, executable, arguments...)
+	}
+	return r.runWithRetry(ctx, buffers, executable, arguments...)
+}
+
+// Runs the command a single time.
+func (r *CmdRunner) runOnce(ctx context.Context, buffers cmdOutputBuffers, executable string, arguments ...string) (*CmdResult, error) {
+	execution := r.newExecution(ctx, buffers, executable, arguments...)
+	return execution.finish(execution.start())
+}
+
+// Creates the spec of the command with the settings of the runner, the input and output are set when it is started.
+func (r *CmdRunner) newSpec(ctx context.Context, executable string, arguments ...string) *CmdSpec {
 	// Remove empty arguments that might cause issues on some platforms (e.g. Windows)
-	arguments = slices.DeleteFunc(arguments, func(arg string) bool {
+	arguments = slices.DeleteFunc(slices.Clone(arguments), func(arg string) bool {
 		return arg == ""
 	})
-	cmd := exec.Command(executable, arguments...)
-	// Set the working directory
-	if r.WorkingDirectory != "" {
-		cmd.Dir = r.WorkingDirectory
-	}
-	// Set the additional environment variables
-	if len(r.AdditionalEnv) > 0 {
-		// Make sure to add the current environment variables first
-		cmd.Env = os.Environ()
-		// Then add the additional ones
-		for k, v := range r.AdditionalEnv {
-			cmd.Env = append(cmd.Env, k+"="+v)
+	spec := &CmdSpec{
+		Context:          ctx,
+		Executable:       executable,
+		Arguments:        arguments,
+		WorkingDirectory: r.WorkingDirectory,
+		GracePeriod:      r.GracePeriod,
+		KillProcessGroup: r.KillProcessGroup,
+	}
+	// Only set the environment if it differs from the current one
+	if r.CleanEnv || len(r.AdditionalEnv) > 0 || len(r.RemovedEnvKeys) > 0 || len(r.PathPrepend) > 0 || len(r.PathAppend) > 0 {
+		spec.Env = r.buildEnv(os.Environ())
+	}
+	return spec
+}
+
+// Builds the environment of the command from the given one with the inherited, removed and added variables.
+// Each variable is only added once so the added values win on every platform.
+func (r *CmdRunner) buildEnv(currentEnv []string) []string {
+	env := []string{}
+	for _, entry := range currentEnv {
+		key := envEntryKey(entry)
+		if (r.CleanEnv && !containsEnvKey(r.InheritEnvKeys, key)) || containsEnvKey(r.RemovedEnvKeys, key) {
+			continue
+		}
+		env = setEnvEntry(env, key, entry)
+	}
+	for key, value := range MapSortedByKey(r.AdditionalEnv) {
+		env = setEnvEntry(env, key, key+"="+value)
+	}
+	if len(r.PathPrepend) > 0 || len(r.PathAppend) > 0 {
+		// Keep the name of an existing variable as it might differ in case on Windows (like Path)
+		pathKey, pathValue := "PATH", ""
+		for _, entry := range env {
+			if key, value := splitEnvEntry(entry); envKeysEqual(key, pathKey) {
+				pathKey, pathValue = key, value
+			}
+		}
+		pathValue = joinPathList(mergePathList(r.absolutePaths(r.PathPrepend), pathValue, r.absolutePaths(r.PathAppend)))
+		env = setEnvEntry(env, pathKey, pathKey+"="+pathValue)
+	}
+	return env
+}
+
+// Resolves relative paths against the working directory of the runner.
+func (r *CmdRunner) absolutePaths(paths []string) []string {
+	absolutePaths := make([]string, len(paths))
+	for index, path := range paths {
+		if !filepath.IsAbs(path) {
+			if absolutePath, err := filepath.Abs(filepath.Join(r.WorkingDirectory, path)); err == nil {
+				path = absolutePath
+			}
+		}
+		absolutePaths[index] = path
+	}
+	return absolutePaths
+}
+
+// Appends the entry to the environment and removes previous entries with the same key.
+func setEnvEntry(env []string, key string, entry string) []string {
+	env = slices.DeleteFunc(env, func(existing string) bool {
+		return envKeysEqual(envEntryKey(existing), key)
+	})
+	return append(env, entry)
+}
+
+// Checks if the keys contain the given key of an environment variable.
+func containsEnvKey(keys []string, key string) bool {
+	return slices.ContainsFunc(keys, func(otherKey string) bool {
+		return envKeysEqual(otherKey, key)
+	})
+}
+
+// Gets the key of an environment entry in the form key=value.
+func envEntryKey(entry string) string {
+	key, _ := splitEnvEntry(entry)
+	return key
+}
+
+// Splits an environment entry in the form key=value into the key and the value.
+func splitEnvEntry(entry string) (string, string) {
+	// Skip the first character as special variables on Windows start with = (like =C:=C:\)
+	if len(entry) > 0 {
+		if index := strings.IndexByte(entry[1:], '='); index >= 0 {
+			return entry[:index+1], entry[index+2:]
 		}
 	}
-	return cmd
+	return entry, ""
+}
+
+// Checks if the keys of environment variables are equal, they are case-insensitive on Windows.
+func envKeysEqual(key1, key2 string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(key1, key2)
+	}
+	return key1 == key2
+}
+
+// Gets the executor of the runner or the default one.
+func (r *CmdRunner) executor() CmdExecutor {
+	if r.Executor != nil {
+		return r.Executor
+	}
+	return CmdExecExecutor
+}
+
+// Marks the error of a command that was stopped because the context was done.
+func (r *CmdRunner) contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w (%w): %w", ErrCmdTimeout, ctx.Err(), err)
+	}
+	return fmt.Errorf("%w (%w): %w", ErrCmdCanceled, ctx.Err(), err)
 }
 
 func (r *CmdRunner) processOutputString(value string) string {
 	return StringTrimNewlineSuffix(value)
 }
 
-func (r *CmdRunner) prepareWriters(stdoutBuf, stderrBuf *bytes.Buffer) (stdoutWriter, stderrWriter io.Writer, cleanup func(), err error) {
-	cleanup = func() {}
-	// Prepare the slices of the writers
-	var stdoutWriters, stderrWriters []io.Writer
-	// Add the console writers if needed
+// Gets the (post-processed) content of the buffer or an empty string if the output was not captured.
+func (r *CmdRunner) bufferString(buffer *outputBuffer) string {
+	if buffer == nil {
+		return ""
+	}
+	if r.SkipPostProcessOutput {
+		return buffer.String()
+	}
+	return r.processOutputString(buffer.String())
+}
+
+// Writes an informational line (not part of the command output) to the console and the log file (if it is not a template).
+func (r *CmdRunner) writeNote(note string) error {
+	line := note + "\n"
 	if r.OutputToConsole {
-		stdoutWriters = append(stdoutWriters, os.Stdout)
-		stderrWriters = append(stderrWriters, os.Stderr)
+		os.Stderr.WriteString(line)
 	}
-	// Add the log file writer if needed
-	if r.LogFilePath != "" {
-		if err := os.MkdirAll(filepath.Dir(r.LogFilePath), os.ModePerm); err != nil {
-			return nil, nil, nil, err
+	// With a template, each command has its own file and the notes are only written to the console
+	if r.LogFilePath != "" && !r.isLogFileTemplate() {
+		logFile, err := openLogFile(r.LogFilePath)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		if _, err := logFile.WriteString(line); err != nil {
+			return err
 		}
-		logFile, err := os.OpenFile(r.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	return nil
+}
+
+func (r *CmdRunner) prepareStdin() (stdin io.Reader, cleanup func(), err error) {
+	cleanup = func() {}
+	if r.StdinFilePath != "" {
+		stdinFile, err := os.Open(r.StdinFilePath)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, err
+		}
+		return stdinFile, func() { stdinFile.Close() }, nil
+	}
+	// Read sized readers from the start so the runner can be re-used
+	if sizedReader, ok := r.Stdin.(interface {
+		io.ReaderAt
+		Size() int64
+	}); ok {
+		return io.NewSectionReader(sizedReader, 0, sizedReader.Size()), cleanup, nil
+	}
+	return r.Stdin, cleanup, nil
+}
+
+func (r *CmdRunner) prepareWriters(buffers cmdOutputBuffers, logStdout, logStderr io.Writer, mutex *sync.Mutex) (stdoutWriter, stderrWriter io.Writer, cleanup func(), err error) {
+	// Collect the cleanup functions which are run in reverse order
+	var cleanups []func()
+	cleanup = func() {
+		for index := len(cleanups) - 1; index >= 0; index-- {
+			cleanups[index]()
 		}
-		cleanup = func() {
-			logFile.Close()
+	}
+	// Prepare the slices of the writers
+	var stdoutWriters, stderrWriters []io.Writer
+	// Add the console writers if needed
+	if r.OutputToConsole {
+		if r.formatsConsoleOutput() {
+			stdoutConsoleWriter := r.newConsoleWriter(os.Stdout, false)
+			stderrConsoleWriter := r.newConsoleWriter(os.Stderr, true)
+			cleanups = append(cleanups, stdoutConsoleWriter.Flush, stderrConsoleWriter.Flush)
+			stdoutWriters = append(stdoutWriters, stdoutConsoleWriter)
+			stderrWriters = append(stderrWriters, stderrConsoleWriter)
+		} else {
+			stdoutWriters = append(stdoutWriters, os.Stdout)
+			stderrWriters = append(stderrWriters, os.Stderr)
 		}
-		stdoutWriters = append(stdoutWriters, logFile)
-		stderrWriters = append(stderrWriters, logFile)
+	}
+	// Add the log file writers if needed
+	if logStdout != nil {
+		stdoutWriters = append(stdoutWriters, logStdout)
+		stderrWriters = append(stderrWriters, logStderr)
+	}
+	// Add the custom writers
+	stdoutWriters = append(stdoutWriters, r.StdoutWriters...)
+	stderrWriters = append(stderrWriters, r.StderrWriters...)
+	// Add the line writers and make sure the last line is flushed at the end
+	for _, lineFunc := range r.StdoutLineFuncs {
+		lineWriter := newLineWriter(lineFunc, r.OutputLimit)
+		cleanups = append(cleanups, lineWriter.Flush)
+		stdoutWriters = append(stdoutWriters, lineWriter)
+	}
+	for _, lineFunc := range r.StderrLineFuncs {
+		lineWriter := newLineWriter(lineFunc, r.OutputLimit)
+		cleanups = append(cleanups, lineWriter.Flush)
+		stderrWriters = append(stderrWriters, lineWriter)
 	}
 	// Add the buffer writers if needed
-	if stdoutBuf != nil {
-		stdoutWriters = append(stdoutWriters, stdoutBuf)
+	if buffers.stdout != nil {
+		stdoutWriters = append(stdoutWriters, buffers.stdout)
+	}
+	if buffers.stderr != nil {
+		stderrWriters = append(stderrWriters, buffers.stderr)
+	}
+	if buffers.combined != nil {
+		stdoutWriters = append(stdoutWriters, buffers.combined)
+		stderrWriters = append(stderrWriters, buffers.combined)
 	}
-	if stderrBuf != nil {
-		stderrWriters = append(stderrWriters, stderrBuf)
+	if buffers.stderrTail != nil {
+		stderrWriters = append(stderrWriters, buffers.stderrTail)
 	}
 	// If no writers were added, add a dummy one to avoid nil writers
 	if len(stdoutWriters) == 0 {
@@ -228,5 +755,8 @@ func (r *CmdRunner) prepareWriters(stdoutBuf, stderrBuf *bytes.Buffer) (stdoutWr
 	if len(stderrWriters) == 0 {
 		stderrWriters = append(stderrWriters, io.Discard)
 	}
-	return io.MultiWriter(stdoutWriters...), io.MultiWriter(stderrWriters...), cleanup, nil
+	// Serialize the writes as stdout and stderr are written concurrently and can share writers
+	stdoutWriter = &lockedWriter{mutex: mutex, writer: io.MultiWriter(stdoutWriters...)}
+	stderrWriter = &lockedWriter{mutex: mutex, writer: io.MultiWriter(stderrWriters...)}
+	return stdoutWriter, stderrWriter, cleanup, nil
 }
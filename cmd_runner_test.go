@@ -1,9 +1,16 @@
 package goext
 
 import (
+	"context"
+	"errors"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRun(t *testing.T) {
@@ -133,3 +140,277 @@ func TestCmdRunnerWithFileAndSeparateOutput(t *testing.T) {
 		t.Errorf("Expected log content to be %q but got %q", "Hello File Output", string(logContent))
 	}
 }
+
+func TestCmdRunnerWithTimeout(t *testing.T) {
+	start := time.Now()
+	executable, arguments := testShellCommand(testSleepScript(5))
+	err := NewCmdRunner().WithTimeout(200*time.Millisecond).Run(executable, arguments...)
+	if !errors.Is(err, ErrCmdTimeout) {
+		t.Errorf("Expected timeout error but got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to wrap %v but got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected command to be killed early but it ran for %v", elapsed)
+	}
+}
+
+func TestCmdRunnerWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	executable, arguments := testShellCommand(testSleepScript(5))
+	_, _, err := NewCmdRunner().RunGetOutputContext(ctx, executable, arguments...)
+	if !errors.Is(err, ErrCmdCanceled) {
+		t.Errorf("Expected canceled error but got %v", err)
+	}
+	if errors.Is(err, ErrCmdTimeout) {
+		t.Errorf("Expected error to not be a timeout but got %v", err)
+	}
+}
+
+func TestCmdRunnerWithContextFailsOnItsOwn(t *testing.T) {
+	executable, arguments := testShellCommand("exit 3")
+	err := NewCmdRunner().RunContext(context.Background(), executable, arguments...)
+	if errors.Is(err, ErrCmdTimeout) || errors.Is(err, ErrCmdCanceled) {
+		t.Errorf("Expected a plain command error but got %v", err)
+	}
+	if exitCode := Cmd.ErrorExitCode(err); exitCode != 3 {
+		t.Errorf("Expected exit code %d but got %d", 3, exitCode)
+	}
+}
+
+// The newline that is written by the shell on the current platform.
+var testNewline = Ternary(runtime.GOOS == "windows", "\r\n", "\n")
+
+// Returns the shell executable and arguments to run the given script on the current platform.
+func testShellCommand(script string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", script}
+	}
+	return "sh", []string{"-c", script}
+}
+
+// Returns a shell script that sleeps for the given amount of seconds.
+func testSleepScript(seconds int) string {
+	if runtime.GOOS == "windows" {
+		return "ping -n " + strconv.Itoa(seconds+1) + " 127.0.0.1 >NUL"
+	}
+	return "sleep " + strconv.Itoa(seconds)
+}
+
+func TestCmdRunnerWithStdinString(t *testing.T) {
+	runner := NewCmdRunner().WithStdinString("hello stdin\n")
+	executable, arguments := testCatCommand()
+	// Run twice to make sure the input can be re-used
+	for range 2 {
+		stdout, _, err := runner.RunGetOutput(executable, arguments...)
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+		if stdout != "hello stdin" {
+			t.Errorf("Expected stdout to be %q but got %q", "hello stdin", stdout)
+		}
+	}
+}
+
+func TestCmdRunnerWithStdinFile(t *testing.T) {
+	inputFilePath := "test_input.txt"
+	defer os.Remove(inputFilePath)
+	if err := os.WriteFile(inputFilePath, []byte("hello file\n"), os.ModePerm); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	executable, arguments := testCatCommand()
+	stdout, _, err := NewCmdRunner().WithStdinFile(inputFilePath).RunGetOutput(executable, arguments...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if stdout != "hello file" {
+		t.Errorf("Expected stdout to be %q but got %q", "hello file", stdout)
+	}
+}
+
+// Returns a command that writes its input to the output on the current platform.
+func testCatCommand() (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "findstr", []string{"^"}
+	}
+	return "cat", nil
+}
+
+func TestCmdRunnerWithLineFuncsAndWriters(t *testing.T) {
+	var stdoutLines, stderrLines []string
+	var stdoutWriter, stderrWriter strings.Builder
+	runner := NewCmdRunner().
+		WithStdoutLineFunc(func(line string) { stdoutLines = append(stdoutLines, line) }).
+		WithStderrLineFunc(func(line string) { stderrLines = append(stderrLines, line) }).
+		WithStdoutWriter(&stdoutWriter).
+		WithStderrWriter(&stderrWriter)
+	executable, arguments := testShellCommand("echo line1&& echo line2&& echo err1>&2")
+	if err := runner.Run(executable, arguments...); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if !slices.Equal(stdoutLines, []string{"line1", "line2"}) {
+		t.Errorf("Expected stdout lines to be %q but got %q", []string{"line1", "line2"}, stdoutLines)
+	}
+	if !slices.Equal(stderrLines, []string{"err1"}) {
+		t.Errorf("Expected stderr lines to be %q but got %q", []string{"err1"}, stderrLines)
+	}
+	if StringTrimNewlineSuffix(stdoutWriter.String()) != "line1"+testNewline+"line2" {
+		t.Errorf("Expected stdout writer to contain both lines but got %q", stdoutWriter.String())
+	}
+	if StringTrimNewlineSuffix(stderrWriter.String()) != "err1" {
+		t.Errorf("Expected stderr writer to be %q but got %q", "err1", stderrWriter.String())
+	}
+}
+
+func TestCmdRunnerRunResult(t *testing.T) {
+	executable, arguments := testShellCommand("echo out&& echo err>&2&& exit 2")
+	result, err := NewCmdRunner().RunResult(executable, arguments...)
+	if Cmd.ErrorExitCode(err) != 2 {
+		t.Errorf("Expected exit code error %d but got %v", 2, err)
+	}
+	if result.ExitCode != 2 {
+		t.Errorf("Expected exit code %d but got %d", 2, result.ExitCode)
+	}
+	if result.Stdout != "out" {
+		t.Errorf("Expected stdout to be %q but got %q", "out", result.Stdout)
+	}
+	if result.Stderr != "err" {
+		t.Errorf("Expected stderr to be %q but got %q", "err", result.Stderr)
+	}
+	if !strings.Contains(result.CombinedOutput, "out") || !strings.Contains(result.CombinedOutput, "err") {
+		t.Errorf("Expected combined output to contain stdout and stderr but got %q", result.CombinedOutput)
+	}
+	if result.Executable != executable || !slices.Equal(result.Arguments, arguments) {
+		t.Errorf("Expected command %q %q but got %q %q", executable, arguments, result.Executable, result.Arguments)
+	}
+	if !strings.HasPrefix(result.CommandLine, executable+" ") {
+		t.Errorf("Expected command line to start with %q but got %q", executable, result.CommandLine)
+	}
+	if pwd, _ := os.Getwd(); result.WorkingDirectory != pwd {
+		t.Errorf("Expected working directory to be %q but got %q", pwd, result.WorkingDirectory)
+	}
+	if result.Pid <= 0 {
+		t.Errorf("Expected a pid but got %d", result.Pid)
+	}
+	if result.StartTime.IsZero() || result.EndTime.Before(result.StartTime) || result.Duration != result.EndTime.Sub(result.StartTime) {
+		t.Errorf("Expected valid times but got %v - %v (%v)", result.StartTime, result.EndTime, result.Duration)
+	}
+}
+
+func TestCmdRunnerRunResultNotFound(t *testing.T) {
+	result, err := NewCmdRunner().RunResult("this-executable-does-not-exist")
+	if err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+	if result.ExitCode != -1 || result.Pid != 0 {
+		t.Errorf("Expected exit code -1 and no pid but got %d and %d", result.ExitCode, result.Pid)
+	}
+}
+
+func TestCmdRunnerCmdError(t *testing.T) {
+	executable, arguments := testShellCommand("echo err1>&2&& echo err2>&2&& echo err3>&2&& exit 4")
+	err := NewCmdRunner().WithErrorStderrLines(2).Run(executable, arguments...)
+	var cmdErr *CmdError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a CmdError but got %v", err)
+	}
+	if cmdErr.Executable != executable || !slices.Equal(cmdErr.Arguments, arguments) {
+		t.Errorf("Expected command %q %q but got %q %q", executable, arguments, cmdErr.Executable, cmdErr.Arguments)
+	}
+	if cmdErr.ExitCode != 4 || Cmd.ErrorExitCode(err) != 4 {
+		t.Errorf("Expected exit code %d but got %d and %d", 4, cmdErr.ExitCode, Cmd.ErrorExitCode(err))
+	}
+	if !slices.Equal(cmdErr.StderrTail, []string{"err2", "err3"}) {
+		t.Errorf("Expected stderr tail to be %q but got %q", []string{"err2", "err3"}, cmdErr.StderrTail)
+	}
+	if !strings.Contains(err.Error(), cmdErr.CommandLine) || !strings.HasSuffix(err.Error(), "err2\n  err3") {
+		t.Errorf("Expected error message to contain the command line and stderr but got %q", err.Error())
+	}
+}
+
+func TestCmdRunnerWithAllowedExitCodes(t *testing.T) {
+	runner := NewCmdRunner().WithAllowedExitCodes(1, 2)
+	executable, arguments := testShellCommand("exit 1")
+	result, err := runner.RunResult(executable, arguments...)
+	if err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if result.ExitCode != 1 {
+		t.Errorf("Expected exit code %d but got %d", 1, result.ExitCode)
+	}
+	executable, arguments = testShellCommand("exit 3")
+	if err := runner.Run(executable, arguments...); Cmd.ErrorExitCode(err) != 3 {
+		t.Errorf("Expected exit code error %d but got %v", 3, err)
+	}
+}
+
+func TestCmdRunnerEnvBuilding(t *testing.T) {
+	currentEnv := []string{"A=1", "B=2", "C=3", "B=4", "=C:=C:\\"}
+	tests := []struct {
+		runner   *CmdRunner
+		expected []string
+	}{
+		{NewCmdRunner().WithEnv("A", "5"), []string{"C=3", "B=4", "=C:=C:\\", "A=5"}},
+		{NewCmdRunner().WithoutEnv("B", "C"), []string{"A=1", "=C:=C:\\"}},
+		{NewCmdRunner().WithEnv("B", "5").WithoutEnv("B"), []string{"A=1", "C=3", "=C:=C:\\"}},
+		{NewCmdRunner().WithoutEnv("B").WithEnv("B", "5"), []string{"A=1", "C=3", "=C:=C:\\", "B=5"}},
+		{NewCmdRunner().WithCleanEnv(), []string{}},
+		{NewCmdRunner().WithCleanEnv().WithEnv("D", "6"), []string{"D=6"}},
+		{NewCmdRunner().WithInheritEnv("B", "X").WithEnv("C", "7"), []string{"B=4", "C=7"}},
+	}
+	for index, test := range tests {
+		if env := test.runner.buildEnv(currentEnv); !slices.Equal(env, test.expected) {
+			t.Errorf("Expected environment %d to be %q but got %q", index, test.expected, env)
+		}
+	}
+}
+
+func TestCmdRunnerEnvKeysCase(t *testing.T) {
+	env := NewCmdRunner().WithEnv("path", "new").buildEnv([]string{"Path=old"})
+	expected := Ternary(runtime.GOOS == "windows", []string{"path=new"}, []string{"Path=old", "path=new"})
+	if !slices.Equal(env, expected) {
+		t.Errorf("Expected environment to be %q but got %q", expected, env)
+	}
+}
+
+func TestCmdRunnerWithCleanEnv(t *testing.T) {
+	t.Setenv("GOEXT_TEST_INHERITED", "inherited")
+	t.Setenv("GOEXT_TEST_OTHER", "other")
+	executor := NewFakeCmdExecutor()
+	executor.AllowUnexpected = true
+	runner := NewCmdRunner().WithExecutor(executor)
+
+	if err := runner.Run("tool"); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if err := runner.WithInheritEnv("GOEXT_TEST_INHERITED").Run("tool"); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if err := runner.WithoutEnv("GOEXT_TEST_OTHER").Run("tool"); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	calls := executor.Calls()
+	if calls[0].Env != nil {
+		t.Errorf("Expected the environment to be inherited but got %q", calls[0].Env)
+	}
+	if !slices.Equal(calls[1].Env, []string{"GOEXT_TEST_INHERITED=inherited"}) {
+		t.Errorf("Expected only the inherited variable but got %q", calls[1].Env)
+	}
+	if !slices.Contains(calls[2].Env, "GOEXT_TEST_INHERITED=inherited") || slices.Contains(calls[2].Env, "GOEXT_TEST_OTHER=other") {
+		t.Errorf("Expected the variable to be removed but got %q", calls[2].Env)
+	}
+}
+
+func TestCmdRunnerWithPath(t *testing.T) {
+	separator := string(os.PathListSeparator)
+	usr, local := filepath.Join(string(os.PathSeparator), "usr"), filepath.Join(string(os.PathSeparator), "local")
+	workingDirectory, _ := filepath.Abs("work")
+	runner := NewCmdRunner().WithWorkingDirectory("work").WithPathPrepend(".bin").WithPathPrepend(local).WithPathAppend(usr)
+	env := runner.buildEnv([]string{"PATH=" + usr})
+	expected := "PATH=" + strings.Join([]string{local, filepath.Join(workingDirectory, ".bin"), usr}, separator)
+	if !slices.Equal(env, []string{expected}) {
+		t.Errorf("Expected environment to be %q but got %q", expected, env)
+	}
+}
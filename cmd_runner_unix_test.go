@@ -0,0 +1,156 @@
+//go:build unix
+
+package goext
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdRunnerWithGracePeriod(t *testing.T) {
+	runner := NewCmdRunner().WithTimeout(200 * time.Millisecond).WithGracePeriod(2 * time.Second).WithKillProcessGroup()
+	stdout, _, err := runner.RunGetOutput("sh", "-c", "trap 'echo terminated; exit 0' TERM; sleep 5 & wait")
+	if !errors.Is(err, ErrCmdTimeout) {
+		t.Errorf("Expected timeout error but got %v", err)
+	}
+	if stdout != "terminated" {
+		t.Errorf("Expected stdout to be %q but got %q", "terminated", stdout)
+	}
+}
+
+func TestCmdRunnerWithKillProcessGroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Process state is read from /proc")
+	}
+	runner := NewCmdRunner().WithTimeout(200 * time.Millisecond).WithKillProcessGroup()
+	stdout, _, _ := runner.RunGetOutput("sh", "-c", "sleep 30 & echo $!; wait")
+	pid, err := strconv.Atoi(stdout)
+	if err != nil {
+		t.Fatalf("Expected the pid of the child but got %q", stdout)
+	}
+	// Give the signal some time to be delivered
+	deadline := time.Now().Add(2 * time.Second)
+	for isProcessAlive(pid) && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if isProcessAlive(pid) {
+		t.Errorf("Expected the child process %d to be killed", pid)
+	}
+}
+
+// Checks if the process with the given pid is running (and not a zombie).
+func isProcessAlive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the executable name which is in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestCmdRunnerWithPathLookup(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "goext-test-tool"), []byte("#!/bin/sh\necho local tool\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := NewCmdRunner().WithPathPrepend(binDir).RunGetOutput("goext-test-tool")
+	if err != nil || stdout != "local tool" {
+		t.Errorf("Expected the tool from the prepended path to run but got %q and %v", stdout, err)
+	}
+	if err := NewCmdRunner().Run("goext-test-tool"); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("Expected the tool not to be found without the modified path but got %v", err)
+	}
+}
+
+func TestCmdLookPath(t *testing.T) {
+	workingDirectory := t.TempDir()
+	binDir := filepath.Join(workingDirectory, ".bin")
+	os.Mkdir(binDir, 0755)
+	if err := os.WriteFile(filepath.Join(binDir, "goext-test-tool"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runner := NewCmdRunner().WithWorkingDirectory(workingDirectory).WithPathPrepend(".bin")
+	if path, err := Cmd.LookPath(runner, "goext-test-tool"); err != nil || path != filepath.Join(binDir, "goext-test-tool") {
+		t.Errorf("Expected the tool to be found in %q but got %q and %v", binDir, path, err)
+	}
+	if path, err := Cmd.LookPath(runner, "./.bin/goext-test-tool"); err != nil || path != filepath.Join(binDir, "goext-test-tool") {
+		t.Errorf("Expected the tool to be found relative to the working directory but got %q and %v", path, err)
+	}
+	if _, err := Cmd.LookPath(nil, "goext-test-tool"); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("Expected the tool not to be found without the runner but got %v", err)
+	}
+}
+
+func TestCmdRunnerWaitsForBackgroundChildren(t *testing.T) {
+	// A successful command that leaves a child holding the output must not fail
+	start := time.Now()
+	stdout, _, err := NewCmdRunner().RunGetOutput("sh", "-c", "sleep 2 & echo hi")
+	if err != nil || stdout != "hi" {
+		t.Errorf("Expected the command to succeed with output %q but got %q and %v", "hi", stdout, err)
+	}
+	if duration := time.Since(start); duration < 2*time.Second {
+		t.Errorf("Expected to wait for the output of the child but returned after %v", duration)
+	}
+}
+
+func TestExecCmdHandleStopAfterExit(t *testing.T) {
+	spec := &CmdSpec{
+		Executable:       "sh",
+		Arguments:        []string{"-c", "trap 'exit 0' TERM; sleep 5 & wait"},
+		GracePeriod:      time.Hour,
+		KillProcessGroup: true,
+	}
+	handle, err := CmdExecExecutor.Start(spec)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := handle.Stop(); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	handle.Wait()
+	// The pending kill must be canceled and the (maybe reused) process group must not be killed anymore
+	if handle.(*execCmdHandle).killTimer.Stop() {
+		t.Errorf("Expected the kill timer to be stopped after the command exited")
+	}
+	if err := handle.Kill(); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("Expected killing an exited command to be skipped but got %v", err)
+	}
+	if err := handle.Stop(); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("Expected stopping an exited command to be skipped but got %v", err)
+	}
+}
+
+func TestCmdProcessStopAndKillWithOrphanedChild(t *testing.T) {
+	for _, kill := range []bool{false, true} {
+		// The background child keeps stdout open after the shell was stopped
+		process, err := NewCmdRunner().WithGracePeriod(100*time.Millisecond).Start("sh", "-c", "sleep 5 & sleep 5; wait")
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		start := time.Now()
+		if kill {
+			err = process.Kill()
+		} else {
+			err = process.Stop()
+		}
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+		if _, err := process.Wait(); !errors.Is(err, ErrCmdCanceled) {
+			t.Errorf("Expected a canceled error but got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("Expected Wait to return soon (kill: %v) but it took %v", kill, elapsed)
+		}
+	}
+}
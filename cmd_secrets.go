@@ -0,0 +1,140 @@
+package goext
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+)
+
+// The replacement for secrets in output, errors and command lines.
+const cmdSecretMask = "***"
+
+// Gets the secret values of the runner including the values of the secret environment variables in the given environment.
+// A nil environment uses the environment of the current process. The longest secrets come first.
+func (r *CmdRunner) secretValues(env []string) []string {
+	secrets := slices.Clone(r.Secrets)
+	for _, key := range r.SecretEnvKeys {
+		if env == nil {
+			secrets = append(secrets, os.Getenv(key))
+			continue
+		}
+		for _, entry := range env {
+			if entryKey, value := splitEnvEntry(entry); envKeysEqual(entryKey, key) {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	// Empty secrets would match everywhere
+	secrets = slices.DeleteFunc(secrets, func(secret string) bool {
+		return secret == ""
+	})
+	slices.SortStableFunc(secrets, func(secret1, secret2 string) int {
+		return len(secret2) - len(secret1)
+	})
+	return slices.Compact(secrets)
+}
+
+// Replaces all occurrences of the secrets in the value.
+func maskSecrets(value string, secrets []string) string {
+	if len(secrets) == 0 {
+		return value
+	}
+	masked, _ := maskSecretBytes([]byte(value), secretBytes(secrets), true)
+	return string(masked)
+}
+
+// Converts the secrets to bytes for masking.
+func secretBytes(secrets []string) [][]byte {
+	secretBytes := make([][]byte, len(secrets))
+	for index, secret := range secrets {
+		secretBytes[index] = []byte(secret)
+	}
+	return secretBytes
+}
+
+// Replaces the secrets in the data. If it is not final, the end of the data that might be the start
+// of a secret is not masked but returned as the rest so it can be masked when more data is available.
+func maskSecretBytes(data []byte, secrets [][]byte, final bool) (masked []byte, rest []byte) {
+	masked = make([]byte, 0, len(data))
+	index := 0
+outer:
+	for index < len(data) {
+		remaining := data[index:]
+		// Keep the rest if it can still become a (longer) secret
+		if !final && slices.ContainsFunc(secrets, func(secret []byte) bool { return len(secret) > len(remaining) && bytes.HasPrefix(secret, remaining) }) {
+			return masked, remaining
+		}
+		for _, secret := range secrets {
+			if bytes.HasPrefix(remaining, secret) {
+				masked = append(masked, cmdSecretMask...)
+				index += len(secret)
+				continue outer
+			}
+		}
+		masked = append(masked, data[index])
+		index++
+	}
+	return masked, nil
+}
+
+////////////////////////////////////////////////////////////
+// Mask Writer
+////////////////////////////////////////////////////////////
+
+// A writer that replaces secrets before writing to the underlying writer, also if they are split between writes.
+type maskWriter struct {
+	writer  io.Writer
+	secrets [][]byte
+	pending []byte
+}
+
+func newMaskWriter(writer io.Writer, secrets []string) *maskWriter {
+	return &maskWriter{writer: writer, secrets: secretBytes(secrets)}
+}
+
+func (w *maskWriter) Write(p []byte) (int, error) {
+	masked, rest := maskSecretBytes(append(w.pending, p...), w.secrets, false)
+	w.pending = slices.Clone(rest)
+	if len(masked) > 0 {
+		if _, err := w.writer.Write(masked); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Writes the data that was kept because it might have been the start of a secret.
+func (w *maskWriter) Flush() {
+	if len(w.pending) > 0 {
+		masked, _ := maskSecretBytes(w.pending, w.secrets, true)
+		w.writer.Write(masked)
+		w.pending = nil
+	}
+}
+
+////////////////////////////////////////////////////////////
+// Masked Error
+////////////////////////////////////////////////////////////
+
+// An error with the secrets masked in its message that still wraps the original error.
+type maskedError struct {
+	err     error
+	secrets []string
+}
+
+// Masks the secrets in the message of the error if it contains any.
+func maskError(err error, secrets []string) error {
+	if err == nil || maskSecrets(err.Error(), secrets) == err.Error() {
+		return err
+	}
+	return &maskedError{err: err, secrets: secrets}
+}
+
+func (e *maskedError) Error() string {
+	return maskSecrets(e.err.Error(), e.secrets)
+}
+
+func (e *maskedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,74 @@
+package goext
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMaskWriter(t *testing.T) {
+	var output strings.Builder
+	writer := newMaskWriter(&output, []string{"secret", "sec", "token123"})
+	for _, part := range []string{"a se", "cret b", " tok", "en1", "23 sec", "re", "t s", "e"} {
+		writer.Write([]byte(part))
+	}
+	if expected := "a *** b *** *** "; output.String() != expected {
+		t.Errorf("Expected masked output before flush to be %q but got %q", expected, output.String())
+	}
+	writer.Flush()
+	if expected := "a *** b *** *** se"; output.String() != expected {
+		t.Errorf("Expected masked output to be %q but got %q", expected, output.String())
+	}
+}
+
+func TestCmdRunnerWithSecrets(t *testing.T) {
+	t.Setenv("GOEXT_TEST_TOKEN", "env-token")
+	logFilePath := filepath.Join(t.TempDir(), "test_output.log")
+	var writerOutput strings.Builder
+	var lines []string
+	runner := NewCmdRunner().
+		WithSecrets("s3cr3t").
+		WithSecretEnv("GOEXT_TEST_TOKEN", "GOEXT_TEST_API_KEY").
+		WithEnv("GOEXT_TEST_API_KEY", "api-key").
+		WithLogFile(logFilePath).
+		WithLogHeaders().
+		WithStdoutWriter(&writerOutput).
+		WithStdoutLineFunc(func(line string) { lines = append(lines, line) })
+	executable, arguments := testShellCommand("echo s3cr3t env-token&& echo api-key>&2&& exit 2")
+	result, err := runner.RunResult(executable, arguments...)
+	if Cmd.ErrorExitCode(err) != 2 {
+		t.Errorf("Expected exit code %d but got %v", 2, err)
+	}
+	if result.Stdout != "*** ***" || result.Stderr != "***" || !strings.Contains(result.CombinedOutput, "*** ***") {
+		t.Errorf("Expected the captured output to be masked but got %q and %q", result.Stdout, result.Stderr)
+	}
+	if strings.TrimSpace(writerOutput.String()) != "*** ***" || !slices.Equal(lines, []string{"*** ***"}) {
+		t.Errorf("Expected the writers to get masked output but got %q and %q", writerOutput.String(), lines)
+	}
+	if strings.Contains(result.CommandLine, "s3cr3t") || slices.ContainsFunc(result.Arguments, func(argument string) bool { return strings.Contains(argument, "s3cr3t") }) {
+		t.Errorf("Expected the command line to be masked but got %q", result.CommandLine)
+	}
+	for _, value := range []string{err.Error(), readTestFile(t, logFilePath)} {
+		if strings.Contains(value, "s3cr3t") || strings.Contains(value, "env-token") || strings.Contains(value, "api-key") || !strings.Contains(value, "***") {
+			t.Errorf("Expected the secrets to be masked but got %q", value)
+		}
+	}
+}
+
+func TestCmdRunnerWithSecretsDryRun(t *testing.T) {
+	var dryRunOutput strings.Builder
+	NewCmdRunner().WithDryRun().WithDryRunWriter(&dryRunOutput).WithEnv("TOKEN", "abc").WithSecretEnv("TOKEN").Run("curl", "-H", "Authorization: abc")
+	if expected := "[dry-run] TOKEN=*** curl -H " + formatCommandLine([]string{"Authorization: ***"}) + "\n"; dryRunOutput.String() != expected {
+		t.Errorf("Expected dry-run output to be %q but got %q", expected, dryRunOutput.String())
+	}
+}
+
+func readTestFile(t *testing.T, filePath string) string {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	return string(content)
+}
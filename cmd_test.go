@@ -1,12 +1,14 @@
 package goext
 
 import (
+	"errors"
+	"slices"
 	"testing"
 )
 
 func TestCmdSplitArgs(t *testing.T) {
 	args := Cmd.SplitArgs(`arg1 arg2 "arg 3" "arg \"4\""`)
-	expected := []string{"arg1", "arg2", `"arg 3"`, `"arg \"4\""`}
+	expected := []string{"arg1", "arg2", "arg 3", `arg "4"`}
 	if len(args) != len(expected) {
 		t.Fatalf("Expected %d args, got %d", len(expected), len(args))
 	}
@@ -20,7 +22,7 @@ func TestCmdSplitArgs(t *testing.T) {
 
 func TestCmdSplitArgsMultiple(t *testing.T) {
 	args := Cmd.SplitArgs(`arg1 arg2 "arg 3" "arg \"4\""`, "arg77 arg88", "arg99")
-	expected := []string{"arg1", "arg2", `"arg 3"`, `"arg \"4\""`, "arg77", "arg88", "arg99"}
+	expected := []string{"arg1", "arg2", "arg 3", `arg "4"`, "arg77", "arg88", "arg99"}
 	if len(args) != len(expected) {
 		t.Fatalf("Expected %d args, got %d", len(expected), len(args))
 	}
@@ -31,3 +33,120 @@ func TestCmdSplitArgsMultiple(t *testing.T) {
 		}
 	}
 }
+
+func TestCmdSplitArgsPosix(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{``, []string{}},
+		{"  a \t b\n c  ", []string{"a", "b", "c"}},
+		{`'a b' "c d"`, []string{"a b", "c d"}},
+		{`--name="my value" 'x'"y"z`, []string{"--name=my value", "xyz"}},
+		{`"" ''`, []string{"", ""}},
+		{`a\ b \"c\" \\d`, []string{"a b", `"c"`, `\d`}},
+		{`'a\"b' "a'b" "\$x \\ \" \n"`, []string{`a\"b`, "a'b", `$x \ " \n`}},
+		{"a\\\nb", []string{"ab"}},
+		{`C:\\dir\\file.txt 'C:\dir'`, []string{`C:\dir\file.txt`, `C:\dir`}},
+	}
+	for _, test := range tests {
+		args, err := Cmd.ParseArgs(test.value)
+		if err != nil {
+			t.Errorf("Expected no error for %q but got %v", test.value, err)
+		}
+		if !slices.Equal(args, test.expected) {
+			t.Errorf("Expected %q to be split into %q but got %q", test.value, test.expected, args)
+		}
+	}
+}
+
+func TestCmdParseArgsUnterminatedQuote(t *testing.T) {
+	for _, value := range []string{`a "b c`, `a 'b c`, `"a \"`} {
+		if _, err := Cmd.ParseArgs("x", value); !errors.Is(err, ErrUnterminatedQuote) {
+			t.Errorf("Expected ErrUnterminatedQuote for %q but got %v", value, err)
+		}
+	}
+	// SplitArgs is lenient and closes the quote at the end
+	args := Cmd.SplitArgs(`a "b c`)
+	if expected := []string{"a", "b c"}; !slices.Equal(args, expected) {
+		t.Errorf("Expected %q but got %q", expected, args)
+	}
+}
+
+func TestCmdSplitArgsWindows(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{``, []string{}},
+		{`a b  c`, []string{"a", "b", "c"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`C:\dir\ "C:\my dir\\" x`, []string{`C:\dir\`, `C:\my dir\`, "x"}},
+		{`a\"b a\\"b c" a\\\"b`, []string{`a"b`, `a\b c`, `a\"b`}},
+		{`"a""b" ""`, []string{`a"b "`}},
+		{`"a" ""`, []string{"a", ""}},
+		{`"a""b c"`, []string{`a"b`, "c"}},
+		{`--name="my value"`, []string{"--name=my value"}},
+		{`'a b'`, []string{"'a", "b'"}},
+		{`"a b`, []string{"a b"}},
+	}
+	for _, test := range tests {
+		args := Cmd.SplitArgsWindows(test.value)
+		if !slices.Equal(args, test.expected) {
+			t.Errorf("Expected %q to be split into %q but got %q", test.value, test.expected, args)
+		}
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	tests := map[string]string{
+		"abc":          "abc",
+		"":             "''",
+		"a b":          "'a b'",
+		"it's":         `'it'\''s'`,
+		"--name=x/y.z": "--name=x/y.z",
+		"$HOME":        "'$HOME'",
+	}
+	for argument, expected := range tests {
+		if quoted := Cmd.Quote(argument); quoted != expected {
+			t.Errorf("Expected %q to be quoted as %q but got %q", argument, expected, quoted)
+		}
+	}
+}
+
+func TestCmdQuoteWindows(t *testing.T) {
+	tests := map[string]string{
+		"abc":           "abc",
+		"":              `""`,
+		"a b":           `"a b"`,
+		`C:\dir\`:       `C:\dir\`,
+		`C:\my dir\`:    `"C:\my dir\\"`,
+		`a"b`:           `"a\"b"`,
+		`a\"b`:          `"a\\\"b"`,
+		"a&b":           `"a&b"`,
+		`\\server\path`: `\\server\path`,
+	}
+	for argument, expected := range tests {
+		if quoted := Cmd.QuoteWindows(argument); quoted != expected {
+			t.Errorf("Expected %q to be quoted as %q but got %q", argument, expected, quoted)
+		}
+	}
+}
+
+func TestCmdJoinArgsRoundTrip(t *testing.T) {
+	args := []string{"git", "commit", "-m", `it's "quoted"`, "", "a\tb\nc", `C:\my dir\`, `\\`, `a\"b`, "$HOME", "*", "a&b|c", "ünïcode"}
+	if parsedArgs, err := Cmd.ParseArgs(Cmd.JoinArgs(args...)); err != nil || !slices.Equal(parsedArgs, args) {
+		t.Errorf("Expected %q to round-trip but got %q (%v)", args, parsedArgs, err)
+	}
+	if parsedArgs := Cmd.SplitArgsWindows(Cmd.JoinArgsWindows(args...)); !slices.Equal(parsedArgs, args) {
+		t.Errorf("Expected %q to round-trip on Windows but got %q", args, parsedArgs)
+	}
+	// A first word with "=" would be a variable assignment in a shell
+	args = []string{"FOO=bar", "x=1"}
+	if commandLine := Cmd.JoinArgs(args...); commandLine != "'FOO=bar' x=1" {
+		t.Errorf("Expected the first argument to be quoted but got %q", commandLine)
+	}
+	if parsedArgs, err := Cmd.ParseArgs(Cmd.JoinArgs(args...)); err != nil || !slices.Equal(parsedArgs, args) {
+		t.Errorf("Expected %q to round-trip but got %q (%v)", args, parsedArgs, err)
+	}
+}
@@ -0,0 +1,169 @@
+package goext
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// The error that is returned when a required tool cannot be found.
+	ErrCmdToolMissing = errors.New("tool not found")
+	// The error that is returned when the version of a required tool is too low.
+	ErrCmdToolOutdated = errors.New("tool is outdated")
+	// The error that is returned when the version of a required tool cannot be determined.
+	ErrCmdToolVersionUnknown = errors.New("tool version unknown")
+)
+
+// The default regex to find the version in the output of a tool.
+var cmdToolVersionRegex = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// A tool that is required to run commands.
+type CmdTool struct {
+	// The executable of the tool.
+	Executable string
+	// The minimum version (like 2.30), empty for no version check.
+	MinVersion string
+	// The arguments to print the version (defaults to --version).
+	VersionArguments []string
+	// The regex to find the version in the output. The first group is used if it has one, otherwise the whole match.
+	// Defaults to the first dotted number (like 2.30.1).
+	VersionRegex *regexp.Regexp
+}
+
+// Creates a required tool with an optional minimum version.
+func NewCmdTool(executable string, minVersion string) CmdTool {
+	return CmdTool{Executable: executable, MinVersion: minVersion}
+}
+
+// Sets the arguments to print the version of the tool.
+func (t CmdTool) WithVersionArguments(arguments ...string) CmdTool {
+	t.VersionArguments = arguments
+	return t
+}
+
+// Sets the regex to find the version in the output of the tool.
+func (t CmdTool) WithVersionRegex(versionRegex *regexp.Regexp) CmdTool {
+	t.VersionRegex = versionRegex
+	return t
+}
+
+// The error that is returned when one or more required tools are missing or outdated.
+type CmdToolsError struct {
+	// The errors of the tools that are missing or outdated.
+	ToolErrors []error
+}
+
+func (e *CmdToolsError) Error() string {
+	lines := []string{"required tools are missing or outdated:"}
+	for _, err := range e.ToolErrors {
+		lines = append(lines, "  "+strings.ReplaceAll(err.Error(), "\n", "\n    "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *CmdToolsError) Unwrap() []error {
+	return e.ToolErrors
+}
+
+// Checks that all the tools can be found with the runner and have the minimum version.
+// All tools are checked and a CmdToolsError with all the problems is returned.
+// The runner can be nil to use the current environment. In dry-run mode, the versions are not checked.
+func (cmdNamespace) RequireTools(runner *CmdRunner, tools ...CmdTool) error {
+	if runner == nil {
+		runner = NewCmdRunner()
+	}
+	// Only capture the version output
+	versionRunner := runner.SetConsoleOutput(false)
+	versionRunner.LogFilePath = ""
+	versionRunner.RetryPolicy = nil
+	toolErrors := []error{}
+	for _, tool := range tools {
+		if err := versionRunner.checkTool(tool); err != nil {
+			toolErrors = append(toolErrors, err)
+		}
+	}
+	if len(toolErrors) > 0 {
+		return &CmdToolsError{ToolErrors: toolErrors}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Checks that the tool can be found and has the minimum version.
+func (r *CmdRunner) checkTool(tool CmdTool) error {
+	if _, err := Cmd.LookPath(r, tool.Executable); err != nil {
+		return fmt.Errorf("%s: %w", tool.Executable, ErrCmdToolMissing)
+	}
+	if tool.MinVersion == "" || r.isDryRun() {
+		return nil
+	}
+	minVersion, err := parseVersion(tool.MinVersion)
+	if err != nil {
+		return fmt.Errorf("%s: invalid minimum version %q: %w", tool.Executable, tool.MinVersion, err)
+	}
+	versionArguments := tool.VersionArguments
+	if versionArguments == nil {
+		versionArguments = []string{"--version"}
+	}
+	output, err := r.RunGetCombinedOutput(tool.Executable, versionArguments...)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", tool.Executable, ErrCmdToolVersionUnknown, err)
+	}
+	versionRegex := tool.VersionRegex
+	if versionRegex == nil {
+		versionRegex = cmdToolVersionRegex
+	}
+	match := versionRegex.FindStringSubmatch(output)
+	if match == nil {
+		return fmt.Errorf("%s: %w: no version found in %q", tool.Executable, ErrCmdToolVersionUnknown, output)
+	}
+	versionString := match[0]
+	if len(match) > 1 {
+		versionString = match[1]
+	}
+	version, err := parseVersion(versionString)
+	if err != nil {
+		return fmt.Errorf("%s: %w: invalid version %q: %w", tool.Executable, ErrCmdToolVersionUnknown, versionString, err)
+	}
+	if compareVersions(version, minVersion) < 0 {
+		return fmt.Errorf("%s: %w: version %s is lower than %s", tool.Executable, ErrCmdToolOutdated, versionString, tool.MinVersion)
+	}
+	return nil
+}
+
+// Parses a dotted version (like 2.30.1) into its numbers.
+func parseVersion(version string) ([]int, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	numbers := make([]int, len(parts))
+	for index, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		numbers[index] = number
+	}
+	return numbers, nil
+}
+
+// Compares two versions, missing numbers are treated as 0.
+func compareVersions(version1, version2 []int) int {
+	for index := range max(len(version1), len(version2)) {
+		number1, number2 := 0, 0
+		if index < len(version1) {
+			number1 = version1[index]
+		}
+		if index < len(version2) {
+			number2 = version2[index]
+		}
+		if number1 != number2 {
+			return number1 - number2
+		}
+	}
+	return 0
+}
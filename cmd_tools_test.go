@@ -0,0 +1,69 @@
+package goext
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCmdRequireTools(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not in the PATH")
+	}
+	if err := Cmd.RequireTools(nil, NewCmdTool("go", "1.0").WithVersionArguments("version"), NewCmdTool("go", "")); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+
+	err := Cmd.RequireTools(nil,
+		NewCmdTool("go", "999.1").WithVersionArguments("version"),
+		NewCmdTool("this-executable-does-not-exist", ""),
+		NewCmdTool("go", "1.0").WithVersionArguments("version").WithVersionRegex(regexp.MustCompile(`version (\S+)`)),
+	)
+	var toolsErr *CmdToolsError
+	if !errors.As(err, &toolsErr) || len(toolsErr.ToolErrors) != 3 {
+		t.Fatalf("Expected a tools error with 3 problems but got %v", err)
+	}
+	if !errors.Is(toolsErr.ToolErrors[0], ErrCmdToolOutdated) || !errors.Is(toolsErr.ToolErrors[1], ErrCmdToolMissing) || !errors.Is(toolsErr.ToolErrors[2], ErrCmdToolVersionUnknown) {
+		t.Errorf("Expected outdated, missing and unknown version errors but got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "required tools are missing or outdated:\n  go: tool is outdated: version ") {
+		t.Errorf("Expected a readable error but got %q", err.Error())
+	}
+}
+
+func TestCmdRequireToolsVersionOutput(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not in the PATH")
+	}
+	executor := NewFakeCmdExecutor()
+	executor.Expect("go", "--version").Return("go version 2.25.1\n", "", 0)
+	executor.Expect("go", "--version").Return("go version 2.30\n", "", 0)
+	runner := NewCmdRunner().WithExecutor(executor)
+	if err := Cmd.RequireTools(runner, NewCmdTool("go", "2.30")); !errors.Is(err, ErrCmdToolOutdated) || !strings.Contains(err.Error(), "version 2.25.1 is lower than 2.30") {
+		t.Errorf("Expected an outdated error but got %v", err)
+	}
+	if err := Cmd.RequireTools(runner, NewCmdTool("go", "2.30.0")); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		version1 string
+		version2 string
+		expected int
+	}{
+		{"2.30", "2.30.0", 0},
+		{"2.9", "2.30", -1},
+		{"v1.2.3", "1.2", 1},
+	}
+	for _, test := range tests {
+		version1, _ := parseVersion(test.version1)
+		version2, _ := parseVersion(test.version2)
+		if result := compareVersions(version1, version2); Ternary(result < 0, -1, Ternary(result > 0, 1, 0)) != test.expected {
+			t.Errorf("Expected comparing %s and %s to be %d but got %d", test.version1, test.version2, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package goext
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////
+// Locked Writer
+////////////////////////////////////////////////////////////
+
+// A writer that serializes the writes with a mutex that can be shared with other writers.
+type lockedWriter struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(p)
+}
+
+////////////////////////////////////////////////////////////
+// Line Writer
+////////////////////////////////////////////////////////////
+
+// A writer that calls the given function for each line that was written.
+// Lines longer than the max length (0 for no limit) are truncated and end with a marker.
+type lineWriter struct {
+	lineFunc  func(line string)
+	maxLength int
+	buffer    []byte
+	// The number of bytes that were dropped from the current line.
+	dropped int64
+}
+
+func newLineWriter(lineFunc func(line string), maxLength int) *lineWriter {
+	return &lineWriter{lineFunc: lineFunc, maxLength: maxLength}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for {
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			w.appendToLine(p)
+			break
+		}
+		w.appendToLine(p[:index])
+		w.writeLine()
+		p = p[index+1:]
+	}
+	return written, nil
+}
+
+// Calls the function with the last line if it was not terminated by a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buffer) > 0 || w.dropped > 0 {
+		w.writeLine()
+	}
+}
+
+// Adds the data to the current line but only keeps up to the max length.
+func (w *lineWriter) appendToLine(p []byte) {
+	if w.maxLength > 0 && len(w.buffer)+len(p) > w.maxLength {
+		count := max(0, w.maxLength-len(w.buffer))
+		w.dropped += int64(len(p) - count)
+		p = p[:count]
+	}
+	w.buffer = append(w.buffer, p...)
+}
+
+// Calls the function with the current line and starts a new one.
+func (w *lineWriter) writeLine() {
+	line := bytes.TrimSuffix(w.buffer, []byte{'\r'})
+	if w.dropped > 0 {
+		line = append(trimIncompleteRune(line), ' ')
+		line = append(line, truncatedMarker(w.dropped)...)
+	}
+	w.lineFunc(string(line))
+	w.buffer = w.buffer[:0]
+	w.dropped = 0
+}
+
+////////////////////////////////////////////////////////////
+// Tail Writer
+////////////////////////////////////////////////////////////
+
+// A writer that keeps the last lines that were written.
+type tailWriter struct {
+	lineWriter *lineWriter
+	maxLines   int
+	lines      []string
+}
+
+// Creates a writer that keeps the last lines, each truncated to the max length (0 for no limit).
+func newTailWriter(maxLines int, maxLineLength int) *tailWriter {
+	w := &tailWriter{maxLines: maxLines}
+	w.lineWriter = newLineWriter(func(line string) {
+		w.lines = append(w.lines, line)
+		if len(w.lines) > w.maxLines {
+			w.lines = w.lines[len(w.lines)-w.maxLines:]
+		}
+	}, maxLineLength)
+	return w
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	return w.lineWriter.Write(p)
+}
+
+// Gets the last lines including a line that was not terminated by a newline.
+func (w *tailWriter) Lines() []string {
+	w.lineWriter.Flush()
+	return w.lines
+}
@@ -0,0 +1,19 @@
+package goext
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	writer := newLineWriter(func(line string) { lines = append(lines, line) }, 0)
+	writer.Write([]byte("first li"))
+	writer.Write([]byte("ne\r\nsecond line\nthi"))
+	writer.Write([]byte("rd"))
+	writer.Flush()
+	expected := []string{"first line", "second line", "third"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Expected lines to be %q but got %q", expected, lines)
+	}
+}
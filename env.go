@@ -1,6 +1,12 @@
 package goext
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
 
 type envNamespace int
 
@@ -22,3 +28,48 @@ func (envNamespace) ValueOrDefault(key string, defaultValue string) (string, boo
 	}
 	return defaultValue, false
 }
+
+// Gets the directories of the PATH environment variable without empty and duplicate entries.
+func (envNamespace) PathList() []string {
+	return mergePathList(nil, os.Getenv("PATH"), nil)
+}
+
+// Returns the value of the PATH environment variable with the given directories prepended and duplicates removed.
+func (envNamespace) PathPrepend(dirs ...string) string {
+	return joinPathList(mergePathList(dirs, os.Getenv("PATH"), nil))
+}
+
+// Returns the value of the PATH environment variable with the given directories appended and duplicates removed.
+func (envNamespace) PathAppend(dirs ...string) string {
+	return joinPathList(mergePathList(nil, os.Getenv("PATH"), dirs))
+}
+
+////////////////////////////////////////////////////////////
+// Internal
+////////////////////////////////////////////////////////////
+
+// Merges the directories into a list of paths without empty and duplicate entries (the first one is kept).
+func mergePathList(prependDirs []string, pathValue string, appendDirs []string) []string {
+	dirs := []string{}
+	for _, dir := range slices.Concat(prependDirs, filepath.SplitList(pathValue), appendDirs) {
+		if dir == "" || slices.ContainsFunc(dirs, func(existing string) bool { return pathsEqual(existing, dir) }) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Joins the directories with the list separator of the OS.
+func joinPathList(dirs []string) string {
+	return strings.Join(dirs, string(os.PathListSeparator))
+}
+
+// Checks if the paths point to the same location, they are case-insensitive on Windows.
+func pathsEqual(path1, path2 string) bool {
+	path1, path2 = filepath.Clean(path1), filepath.Clean(path2)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(path1, path2)
+	}
+	return path1 == path2
+}
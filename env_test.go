@@ -2,6 +2,9 @@ package goext
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +35,19 @@ func TestGetEnvOrDefault(t *testing.T) {
 
 	os.Unsetenv("HELLO_VAR")
 }
+
+func TestEnvPathHelpers(t *testing.T) {
+	separator := string(os.PathListSeparator)
+	bin, local, usr := filepath.Join("a", "bin"), filepath.Join("a", "local"), filepath.Join("a", "usr")
+	t.Setenv("PATH", strings.Join([]string{bin, "", usr, bin + string(os.PathSeparator)}, separator))
+
+	if expected := []string{bin, usr}; !slices.Equal(Env.PathList(), expected) {
+		t.Errorf("Expected path list to be %q but got %q", expected, Env.PathList())
+	}
+	if expected := strings.Join([]string{local, bin, usr}, separator); Env.PathPrepend(local, bin) != expected {
+		t.Errorf("Expected prepended path to be %q but got %q", expected, Env.PathPrepend(local, bin))
+	}
+	if expected := strings.Join([]string{bin, usr, local}, separator); Env.PathAppend(usr, local) != expected {
+		t.Errorf("Expected appended path to be %q but got %q", expected, Env.PathAppend(usr, local))
+	}
+}